@@ -0,0 +1,699 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AccessPolicy grants the callers matching Identities access to the tables matching Tables in the databases
+// matching Databases, on the connections matching Connections.
+// Patterns are globs such as "app_*" or regular expressions such as "/^app_[0-9]+$/" (see matchPattern)
+type AccessPolicy struct {
+	Identities  []string `yaml:"identities"`
+	Connections []string `yaml:"connections,omitempty"` // Empty means every connection
+	Databases   []string `yaml:"databases"`
+	Tables      []string `yaml:"tables"` // "table" or "database.table" patterns. Empty means every table
+}
+
+// accessPolicyFile is the structure of the access policy file (YAML or JSON)
+type accessPolicyFile struct {
+	Policies []AccessPolicy `yaml:"policies"`
+}
+
+// LoadAccessPolicies loads the access policies from a YAML or JSON file such as:
+//
+//	policies:
+//	  - identities: [alice, bob]
+//	    databases: ["*"]
+//	  - identities: [contractor-*]
+//	    connections: [staging]
+//	    databases: [app]
+//	    tables: [orders, order_items, "products_*"]
+func LoadAccessPolicies(filename string) ([]AccessPolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var file accessPolicyFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	// An empty file would otherwise hide nothing
+	if len(file.Policies) == 0 {
+		return nil, fmt.Errorf("%s: no policies", filename)
+	}
+	for i, policy := range file.Policies {
+		if len(policy.Identities) == 0 {
+			return nil, fmt.Errorf("%s: policies[%d]: identities is required", filename, i)
+		}
+		if len(policy.Databases) == 0 {
+			return nil, fmt.Errorf("%s: policies[%d]: databases is required", filename, i)
+		}
+		for _, patterns := range [][]string{policy.Identities, policy.Connections, policy.Databases, policy.Tables} {
+			if err := validatePatterns(patterns); err != nil {
+				return nil, fmt.Errorf("%s: policies[%d]: %w", filename, i, err)
+			}
+		}
+	}
+	return file.Policies, nil
+}
+
+// ObjectFilter hides databases and tables from every caller, including stdio clients and the subcommands.
+// A database or table is visible when it matches an allowed pattern (or there are none) and no denied pattern
+type ObjectFilter struct {
+	AllowedDatabases []string `yaml:"allowedDatabases,omitempty"`
+	DeniedDatabases  []string `yaml:"deniedDatabases,omitempty"`
+	AllowedTables    []string `yaml:"allowedTables,omitempty"` // "table" or "database.table" patterns
+	DeniedTables     []string `yaml:"deniedTables,omitempty"`
+}
+
+// IsZero reports whether the filter hides nothing
+func (f ObjectFilter) IsZero() bool {
+	return len(f.AllowedDatabases) == 0 && len(f.DeniedDatabases) == 0 && len(f.AllowedTables) == 0 && len(f.DeniedTables) == 0
+}
+
+// Validate checks that every pattern of the filter is valid
+func (f ObjectFilter) Validate() error {
+	for _, patterns := range [][]string{f.AllowedDatabases, f.DeniedDatabases, f.AllowedTables, f.DeniedTables} {
+		if err := validatePatterns(patterns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f ObjectFilter) databaseVisible(dbName string) bool {
+	return (len(f.AllowedDatabases) == 0 || matchAny(f.AllowedDatabases, dbName)) && !matchAny(f.DeniedDatabases, dbName)
+}
+
+func (f ObjectFilter) tableVisible(dbName string, tableName string) bool {
+	return (len(f.AllowedTables) == 0 || matchTable(f.AllowedTables, dbName, tableName)) && !matchTable(f.DeniedTables, dbName, tableName)
+}
+
+// compiledPatterns caches the regular expressions of the patterns
+var compiledPatterns sync.Map
+
+// compilePattern returns the regular expression of a pattern written as /regexp/, or nil for a glob
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) < 2 || !strings.HasPrefix(pattern, "/") || !strings.HasSuffix(pattern, "/") {
+		return nil, nil
+	}
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// validatePatterns checks that the patterns are valid globs or regular expressions
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		re, err := compilePattern(pattern)
+		if err == nil && re == nil {
+			_, err = path.Match(pattern, "")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchPattern reports whether the name matches a glob such as "app_*", or a regular expression written
+// as /regexp/, which matches anywhere in the name unless it is anchored. Both ignore case.
+// Invalid patterns, which the loaders reject, match nothing
+func matchPattern(pattern string, name string) bool {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return false
+	}
+	if re != nil {
+		return re.MatchString(name)
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// matchAny reports whether the name matches any of the patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPatterns splits a comma-separated list of patterns. Commas inside a /regexp/ don't separate patterns
+func splitPatterns(s string) []string {
+	var patterns []string
+	s = strings.TrimSpace(s)
+	for s != "" {
+		end := strings.Index(s, ",")
+		if strings.HasPrefix(s, "/") {
+			// A regular expression ends at the first slash followed by a comma
+			if i := strings.Index(s[1:], "/,"); i >= 0 {
+				end = i + 2
+			} else {
+				end = -1
+			}
+		}
+		pattern := s
+		if end >= 0 {
+			pattern, s = s[:end], s[end+1:]
+		} else {
+			s = ""
+		}
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+		s = strings.TrimSpace(s)
+	}
+	return patterns
+}
+
+// matchTable reports whether the table matches any of the patterns, by its name or by "database.table"
+func matchTable(patterns []string, dbName string, tableName string) bool {
+	return matchAny(patterns, tableName) || matchAny(patterns, dbName+"."+tableName)
+}
+
+// accessControl decides which databases and tables are visible to the caller of a tool.
+// A nil accessControl makes everything visible
+type accessControl struct {
+	filter     ObjectFilter
+	policies   []AccessPolicy // nil means that every caller can see what the filter doesn't hide
+	connection string         // The name of the connection, matched against the connections of the policies
+}
+
+// policiesFor returns the policies of the caller on the connection. Callers without an identity (e.g. stdio) only match "*"
+func (a *accessControl) policiesFor(ctx context.Context) []AccessPolicy {
+	identity := identityFromContext(ctx)
+	var policies []AccessPolicy
+	for _, policy := range a.policies {
+		if !matchAny(policy.Identities, identity) {
+			continue
+		}
+		if len(policy.Connections) > 0 && !matchAny(policy.Connections, a.connection) {
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// connectionVisible reports whether the caller may see anything on the connection
+func (a *accessControl) connectionVisible(ctx context.Context) bool {
+	if a == nil || a.policies == nil {
+		return true
+	}
+	return len(a.policiesFor(ctx)) > 0
+}
+
+// databaseVisible reports whether the caller may see the database
+func (a *accessControl) databaseVisible(ctx context.Context, dbName string) bool {
+	if a == nil {
+		return true
+	}
+	if !a.filter.databaseVisible(dbName) {
+		return false
+	}
+	if a.policies == nil {
+		return true
+	}
+	for _, policy := range a.policiesFor(ctx) {
+		if matchAny(policy.Databases, dbName) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableVisible reports whether the caller may see the table. tableName may be qualified as "database.table"
+func (a *accessControl) tableVisible(ctx context.Context, dbName string, tableName string) bool {
+	if a == nil {
+		return true
+	}
+	if db, table, ok := strings.Cut(tableName, "."); ok {
+		dbName, tableName = db, table
+	}
+	if !a.filter.databaseVisible(dbName) || !a.filter.tableVisible(dbName, tableName) {
+		return false
+	}
+	if a.policies == nil {
+		return true
+	}
+	for _, policy := range a.policiesFor(ctx) {
+		if !matchAny(policy.Databases, dbName) {
+			continue
+		}
+		if len(policy.Tables) == 0 || matchTable(policy.Tables, dbName, tableName) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDatabase returns an error when the caller may not see the database. The error is the same whether
+// or not the database exists, so that hidden databases can't be discovered
+func (a *accessControl) checkDatabase(ctx context.Context, dbName string) error {
+	if !a.databaseVisible(ctx, dbName) {
+		return fmt.Errorf("access denied to database %s", dbName)
+	}
+	return nil
+}
+
+// redactForeignKeys hides the referenced table and columns of the foreign keys referencing hidden tables,
+// so that the relationship is still shown without revealing the table
+func (a *accessControl) redactForeignKeys(ctx context.Context, dbName string, fks []ForeignKey) []ForeignKey {
+	if a == nil || len(fks) == 0 {
+		return fks
+	}
+	redacted := make([]ForeignKey, 0, len(fks))
+	for _, fk := range fks {
+		if !fk.Redacted && !a.tableVisible(ctx, dbName, fk.RefTable) {
+			fk.RefTable, fk.RefColumns, fk.Redacted = "", []string{}, true
+		}
+		redacted = append(redacted, fk)
+	}
+	return redacted
+}
+
+// visibleSnapshot removes the hidden tables, views and triggers from a snapshot, such as the target of diff_schemas
+func (a *accessControl) visibleSnapshot(ctx context.Context, snapshot SchemaSnapshot) (SchemaSnapshot, error) {
+	if a == nil {
+		return snapshot, nil
+	}
+	if err := a.checkDatabase(ctx, snapshot.Database); err != nil {
+		return SchemaSnapshot{}, err
+	}
+
+	visible := snapshot
+	visible.Tables = nil
+	for _, t := range snapshot.Tables {
+		if a.tableVisible(ctx, snapshot.Database, t.Name) {
+			t.ForeignKeys = a.redactForeignKeys(ctx, snapshot.Database, t.ForeignKeys)
+			visible.Tables = append(visible.Tables, t)
+		}
+	}
+	visible.GeneratedColumns = nil
+	for table, columns := range snapshot.GeneratedColumns {
+		if a.tableVisible(ctx, snapshot.Database, table) {
+			if visible.GeneratedColumns == nil {
+				visible.GeneratedColumns = map[string][]GeneratedColumn{}
+			}
+			visible.GeneratedColumns[table] = columns
+		}
+	}
+	// Other databases are unknown to a snapshot, so only references to its own hidden tables are checked
+	var hidden []string
+	for _, t := range snapshot.Tables {
+		if !a.tableVisible(ctx, snapshot.Database, t.Name) {
+			hidden = append(hidden, t.Name)
+		}
+	}
+	for _, v := range snapshot.Views {
+		if !a.tableVisible(ctx, snapshot.Database, v.Name) {
+			hidden = append(hidden, v.Name)
+		}
+	}
+	databaseExists := func(dbName string) bool { return dbName == snapshot.Database }
+	visible.Views = a.visibleDefinitions(ctx, snapshot.Database, a.visibleObjects(ctx, snapshot.Database, snapshot.Views), hidden, databaseExists)
+	visible.Triggers = a.visibleDefinitions(ctx, snapshot.Database, a.visibleObjects(ctx, snapshot.Database, snapshot.Triggers), hidden, databaseExists)
+	visible.Routines = a.visibleDefinitions(ctx, snapshot.Database, snapshot.Routines, hidden, databaseExists)
+	return visible, nil
+}
+
+// qualifiedNamePattern matches qualified names such as `payroll`.`salaries` or o.id in definitions
+var qualifiedNamePattern = regexp.MustCompile("`?([A-Za-z0-9_$]+)`?\\s*\\.\\s*`?([A-Za-z0-9_$]+)`?")
+
+// visibleDefinitions removes the views, triggers and routines whose definition references a hidden table,
+// so that their definitions don't reveal it. hidden are the hidden tables and views of the database.
+// A qualified name is checked as database.table when databaseExists reports that its qualifier is a database,
+// which tells it apart from alias.column
+func (a *accessControl) visibleDefinitions(ctx context.Context, dbName string, objects []SchemaObject, hidden []string,
+	databaseExists func(string) bool) []SchemaObject {
+	var visible []SchemaObject
+	for _, o := range objects {
+		if a.definitionVisible(ctx, dbName, o.Definition, hidden, databaseExists) {
+			visible = append(visible, o)
+		}
+	}
+	return visible
+}
+
+// definitionVisible reports whether the definition references no hidden table
+func (a *accessControl) definitionVisible(ctx context.Context, dbName string, definition string, hidden []string,
+	databaseExists func(string) bool) bool {
+	for _, name := range hidden {
+		if referencesIdentifier(definition, name) {
+			return false
+		}
+	}
+	for _, match := range qualifiedNamePattern.FindAllStringSubmatch(definition, -1) {
+		qualifier, name := match[1], match[2]
+		if !a.tableVisible(ctx, qualifier, name) && databaseExists(qualifier) {
+			return false
+		}
+	}
+	return true
+}
+
+// visibleObjects removes the views with hidden names and the triggers of hidden tables
+func (a *accessControl) visibleObjects(ctx context.Context, dbName string, objects []SchemaObject) []SchemaObject {
+	var visible []SchemaObject
+	for _, o := range objects {
+		table := o.Name
+		if o.Type == "TRIGGER" {
+			table = o.Table
+		}
+		if a.tableVisible(ctx, dbName, table) {
+			visible = append(visible, o)
+		}
+	}
+	return visible
+}
+
+// accessDB hides the databases and tables that the caller of a tool may not see.
+// Hidden databases are denied, and hidden tables behave as if they didn't exist
+type accessDB struct {
+	DB
+	access *accessControl
+}
+
+// visibleSummaries removes the hidden tables and redacts the foreign keys referencing them, then applies the limit of
+// the filter, which the underlying query can't apply because it doesn't know which tables are hidden
+func (db *accessDB) visibleSummaries(ctx context.Context, dbName string, filter TableFilter,
+	fetch func(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error)) ([]TableSummary, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	unlimited := filter
+	unlimited.Limit = 0
+	tables, err := fetch(ctx, dbName, unlimited)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []TableSummary{}
+	for _, t := range tables {
+		if filter.Limit > 0 && len(visible) == filter.Limit {
+			break
+		}
+		if db.access.tableVisible(ctx, dbName, t.Name) {
+			t.FK = db.access.redactForeignKeys(ctx, dbName, t.FK)
+			visible = append(visible, t)
+		}
+	}
+	return visible, nil
+}
+
+func (db *accessDB) FetchAllTableSummaries(ctx context.Context, dbName string) ([]TableSummary, error) {
+	return db.visibleSummaries(ctx, dbName, TableFilter{}, func(ctx context.Context, dbName string, _ TableFilter) ([]TableSummary, error) {
+		return db.DB.FetchAllTableSummaries(ctx, dbName)
+	})
+}
+
+func (db *accessDB) FetchTableSummaries(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	return db.visibleSummaries(ctx, dbName, filter, db.DB.FetchTableSummaries)
+}
+
+func (db *accessDB) FetchTableWithComments(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	return db.visibleSummaries(ctx, dbName, filter, db.DB.FetchTableWithComments)
+}
+
+// CountTables fetches the tables once and counts the visible ones, since the query can't tell which are hidden
+func (db *accessDB) CountTables(ctx context.Context, dbName string, filter TableFilter) (int, int, error) {
+	all := filter
+	all.After, all.Limit = "", 0
+	tables, err := db.visibleSummaries(ctx, dbName, all, db.DB.FetchTableWithComments)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(tables), countAfter(tables, func(t TableSummary) string { return t.Name }, filter.After), nil
+}
+
+// visibleTable returns an error for a hidden database, and false for a hidden table
+func (db *accessDB) visibleTable(ctx context.Context, dbName string, tableName string) (bool, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return false, err
+	}
+	return db.access.tableVisible(ctx, dbName, tableName), nil
+}
+
+func (db *accessDB) FetchPrimaryKeys(ctx context.Context, dbName string, tableName string) ([]string, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	return db.DB.FetchPrimaryKeys(ctx, dbName, tableName)
+}
+
+func (db *accessDB) FetchUniqueKeys(ctx context.Context, dbName string, tableName string) ([]UniqueKey, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	return db.DB.FetchUniqueKeys(ctx, dbName, tableName)
+}
+
+func (db *accessDB) FetchForeignKeys(ctx context.Context, dbName string, tableName string) ([]ForeignKey, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	fks, err := db.DB.FetchForeignKeys(ctx, dbName, tableName)
+	return db.access.redactForeignKeys(ctx, dbName, fks), err
+}
+
+func (db *accessDB) FetchTableColumns(ctx context.Context, dbName string, tableName string) ([]ColumnInfo, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	return db.DB.FetchTableColumns(ctx, dbName, tableName)
+}
+
+func (db *accessDB) FetchTableIndexes(ctx context.Context, dbName string, tableName string) ([]IndexInfo, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	return db.DB.FetchTableIndexes(ctx, dbName, tableName)
+}
+
+func (db *accessDB) FetchAllColumns(ctx context.Context, dbName string) (map[string][]ColumnInfo, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	columns, err := db.DB.FetchAllColumns(ctx, dbName)
+	for table := range columns {
+		if !db.access.tableVisible(ctx, dbName, table) {
+			delete(columns, table)
+		}
+	}
+	return columns, err
+}
+
+func (db *accessDB) FetchColumns(ctx context.Context, dbName string, tableNames []string) (map[string][]ColumnInfo, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	var visible []string
+	for _, table := range tableNames {
+		if db.access.tableVisible(ctx, dbName, table) {
+			visible = append(visible, table)
+		}
+	}
+	return db.DB.FetchColumns(ctx, dbName, visible)
+}
+
+func (db *accessDB) FetchIndexedColumns(ctx context.Context, dbName string) (map[string]map[string]bool, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	indexed, err := db.DB.FetchIndexedColumns(ctx, dbName)
+	for table := range indexed {
+		if !db.access.tableVisible(ctx, dbName, table) {
+			delete(indexed, table)
+		}
+	}
+	return indexed, err
+}
+
+func (db *accessDB) FetchColumnIndexes(ctx context.Context, dbName string, tableName string, columnName string) ([]IndexInfo, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	return db.DB.FetchColumnIndexes(ctx, dbName, tableName, columnName)
+}
+
+func (db *accessDB) FetchReferencingForeignKeys(ctx context.Context, dbName string, tableName string) ([]DependencyEdge, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	edges, err := db.DB.FetchReferencingForeignKeys(ctx, dbName, tableName)
+	var visible []DependencyEdge
+	for _, edge := range edges {
+		if db.access.tableVisible(ctx, dbName, edge.Table) {
+			visible = append(visible, edge)
+		}
+	}
+	return visible, err
+}
+
+func (db *accessDB) FetchGeneratedColumns(ctx context.Context, dbName string, tableName string) ([]GeneratedColumn, error) {
+	if ok, err := db.visibleTable(ctx, dbName, tableName); !ok {
+		return nil, err
+	}
+	return db.DB.FetchGeneratedColumns(ctx, dbName, tableName)
+}
+
+// visibleDefinitions removes the objects whose definition references a hidden table of the database or of another one
+func (db *accessDB) visibleDefinitions(ctx context.Context, dbName string, objects []SchemaObject) ([]SchemaObject, error) {
+	if len(objects) == 0 {
+		return objects, nil
+	}
+
+	// The tables of MySQL include the views, which the snapshots keep apart
+	tables, err := db.DB.FetchTableWithComments(ctx, dbName, TableFilter{})
+	if err != nil {
+		return nil, err
+	}
+	views, err := db.DB.FetchViews(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	var hidden []string
+	for _, t := range tables {
+		if !db.access.tableVisible(ctx, dbName, t.Name) {
+			hidden = append(hidden, t.Name)
+		}
+	}
+	for _, v := range views {
+		if !db.access.tableVisible(ctx, dbName, v.Name) {
+			hidden = append(hidden, v.Name)
+		}
+	}
+
+	exists := map[string]bool{dbName: true}
+	databaseExists := func(name string) bool {
+		if found, ok := exists[name]; ok {
+			return found
+		}
+		// A qualifier that is not a database, such as a table alias, has no tables
+		tables, err := db.DB.FetchTableWithComments(ctx, name, TableFilter{Limit: 1})
+		exists[name] = err == nil && len(tables) > 0
+		return exists[name]
+	}
+	return db.access.visibleDefinitions(ctx, dbName, objects, hidden, databaseExists), nil
+}
+
+func (db *accessDB) FetchViews(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	views, err := db.DB.FetchViews(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	return db.visibleDefinitions(ctx, dbName, db.access.visibleObjects(ctx, dbName, views))
+}
+
+func (db *accessDB) FetchTriggers(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	triggers, err := db.DB.FetchTriggers(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	return db.visibleDefinitions(ctx, dbName, db.access.visibleObjects(ctx, dbName, triggers))
+}
+
+// FetchRoutines doesn't return the routines referencing hidden tables
+func (db *accessDB) FetchRoutines(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	routines, err := db.DB.FetchRoutines(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	return db.visibleDefinitions(ctx, dbName, routines)
+}
+
+// FetchObjectTypes doesn't report tables and views with hidden names, nor triggers of hidden tables,
+// nor views, triggers and routines referencing hidden tables
+func (db *accessDB) FetchObjectTypes(ctx context.Context, dbName string, name string) ([]string, error) {
+	if err := db.access.checkDatabase(ctx, dbName); err != nil {
+		return nil, err
+	}
+	types, err := db.DB.FetchObjectTypes(ctx, dbName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var visible []string
+	for _, t := range types {
+		switch t {
+		case "TABLE":
+			if !db.access.tableVisible(ctx, dbName, name) {
+				continue
+			}
+		case "VIEW", "TRIGGER", "PROCEDURE", "FUNCTION":
+			// The objects are already limited to the visible ones
+			fetch := db.FetchRoutines
+			switch t {
+			case "VIEW":
+				fetch = db.FetchViews
+			case "TRIGGER":
+				fetch = db.FetchTriggers
+			}
+			objects, err := fetch(ctx, dbName)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.ContainsFunc(filterObjects(objects, name), func(o SchemaObject) bool { return o.Type == t }) {
+				continue
+			}
+		}
+		visible = append(visible, t)
+	}
+	return visible, nil
+}
+
+func (db *accessDB) FetchCreateStatement(ctx context.Context, dbName string, objectType string, name string) (string, error) {
+	types, err := db.FetchObjectTypes(ctx, dbName, name)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range types {
+		if strings.EqualFold(t, objectType) {
+			return db.DB.FetchCreateStatement(ctx, dbName, objectType, name)
+		}
+	}
+	return "", fmt.Errorf("%s %s does not exist", strings.ToLower(objectType), name)
+}
+
+// filterObjects returns the objects with the name
+func filterObjects(objects []SchemaObject, name string) []SchemaObject {
+	var found []SchemaObject
+	for _, o := range objects {
+		if o.Name == name {
+			found = append(found, o)
+		}
+	}
+	return found
+}
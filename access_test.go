@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAccessPolicies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "policies.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	policies, err := LoadAccessPolicies(write(`
+policies:
+  - identities: [alice, bob]
+    databases: ["*"]
+  - identities: [contractor-*]
+    connections: [staging]
+    databases: [app]
+    tables: [orders, "app.user*"]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []AccessPolicy{
+		{Identities: []string{"alice", "bob"}, Databases: []string{"*"}},
+		{Identities: []string{"contractor-*"}, Connections: []string{"staging"}, Databases: []string{"app"}, Tables: []string{"orders", "app.user*"}},
+	}, policies)
+
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+	}{
+		{name: "no policies", content: "policies: []", errorMsg: "no policies"},
+		{name: "no identities", content: "policies:\n  - databases: [app]", errorMsg: "policies[0]: identities is required"},
+		{name: "no databases", content: "policies:\n  - identities: [alice]", errorMsg: "policies[0]: databases is required"},
+		{name: "invalid pattern", content: "policies:\n  - identities: [alice]\n    databases: [\"app[\"]", errorMsg: `invalid pattern "app["`},
+		{name: "unknown field", content: "policies:\n  - identities: [alice]\n    database: [app]", errorMsg: "field database not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadAccessPolicies(write(tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+// tableListingDB counts the queries listing the tables
+type tableListingDB struct {
+	DB
+	calls int
+}
+
+func (db *tableListingDB) FetchTableWithComments(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	db.calls++
+	return db.DB.FetchTableWithComments(ctx, dbName, filter)
+}
+
+func TestHandler_AccessPolicies(t *testing.T) {
+	snapshots, err := ParseDDL(`
+CREATE DATABASE app;
+USE app;
+CREATE TABLE users (id INT PRIMARY KEY) COMMENT='Users';
+CREATE TABLE orders (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE TABLE salaries (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL,
+    CONSTRAINT fk_salary_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE TABLE audit_logs (
+    id INT PRIMARY KEY,
+    salary_id INT,
+    CONSTRAINT fk_salary FOREIGN KEY (salary_id) REFERENCES salaries(id)
+);
+CREATE VIEW user_orders AS select `+"`o`.`id` AS `id`, `u`.`id` AS `user_id` from (`app`.`orders` `o` join `app`.`users` `u` on((`o`.`user_id` = `u`.`id`)))"+`;
+CREATE VIEW user_salaries AS select `+"`u`.`id` AS `id` from (`app`.`users` `u` join `app`.`salaries` `s` on((`s`.`user_id` = `u`.`id`)))"+`;
+CREATE VIEW user_payslips AS select `+"`u`.`id` AS `id` from (`app`.`users` `u` join `payroll`.`payslips` `p` on((`p`.`id` = `u`.`id`)))"+`;
+DELIMITER ;;
+CREATE PROCEDURE raise_salary(p_user INT)
+BEGIN
+  UPDATE salaries SET amount = amount + 1 WHERE user_id = p_user;
+END ;;
+CREATE FUNCTION user_count() RETURNS INT
+BEGIN
+  RETURN (SELECT COUNT(*) FROM users);
+END ;;
+DELIMITER ;
+CREATE DATABASE payroll;
+USE payroll;
+CREATE TABLE payslips (id INT PRIMARY KEY);
+`, "app")
+	require.NoError(t, err)
+	db, err := NewSnapshotDB(snapshots)
+	require.NoError(t, err)
+
+	handler := NewHandler(db, "", WithAccessPolicies([]AccessPolicy{
+		{Identities: []string{"admin"}, Databases: []string{"*"}},
+		{Identities: []string{"contractor-*"}, Databases: []string{"app"}, Tables: []string{"users", "orders", "audit_*", "user_*"}},
+	}))
+	admin := withIdentity(t.Context(), "admin")
+	contractor := withIdentity(t.Context(), "contractor-acme")
+
+	call := func(ctx context.Context, tool func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := tool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: args}})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("list_tables", func(t *testing.T) {
+		result := call(contractor, handler.ListTables, map[string]interface{}{"dbName": "app", "format": "json"})
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		data := result.StructuredContent.(ListTablesData)
+		assert.Equal(t, 3, data.Total)
+		var names []string
+		for _, table := range data.Tables {
+			names = append(names, table.Name)
+		}
+		assert.Equal(t, []string{"audit_logs", "orders", "users"}, names)
+		assert.Equal(t, []ForeignKey{{Name: "fk_salary", Columns: []string{"salary_id"}, RefColumns: []string{}, Redacted: true}}, data.Tables[0].FK,
+			"foreign keys to hidden tables are redacted")
+
+		result = call(contractor, handler.ListTables, map[string]interface{}{"dbName": "app", "format": "json", "limit": 2})
+		data = result.StructuredContent.(ListTablesData)
+		assert.Equal(t, 2, data.Returned)
+		assert.Equal(t, 1, data.Omitted, "hidden tables are not counted as omitted")
+
+		result = call(admin, handler.ListTables, map[string]interface{}{"dbName": "app", "format": "json"})
+		assert.Equal(t, 4, result.StructuredContent.(ListTablesData).Total)
+	})
+
+	t.Run("list_tables counts the visible tables once per page", func(t *testing.T) {
+		listing := &tableListingDB{DB: db}
+		handler := NewHandler(listing, "", WithAccessPolicies([]AccessPolicy{
+			{Identities: []string{"contractor-*"}, Databases: []string{"app"}, Tables: []string{"users", "orders", "audit_*"}},
+		}))
+		result := call(contractor, handler.ListTables, map[string]interface{}{"dbName": "app", "format": "json", "limit": 1, "detail": "names"})
+		data := result.StructuredContent.(ListTablesData)
+		assert.Equal(t, 3, data.Total)
+		assert.Equal(t, 2, data.Omitted)
+		assert.Equal(t, 2, listing.calls, "the page and the counts")
+	})
+
+	t.Run("hidden database", func(t *testing.T) {
+		for _, dbName := range []string{"payroll", "missing"} {
+			result := call(contractor, handler.ListTables, map[string]interface{}{"dbName": dbName})
+			assert.True(t, result.IsError)
+			assert.Equal(t, "access denied to database "+dbName, result.Content[0].(mcp.TextContent).Text,
+				"hidden and missing databases can't be told apart")
+		}
+
+		result := call(admin, handler.ListTables, map[string]interface{}{"dbName": "payroll"})
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("describe_tables", func(t *testing.T) {
+		result := call(contractor, handler.DescribeTables, map[string]interface{}{
+			"dbName": "app", "tableNames": []interface{}{"salaries", "orders"}, "format": "json",
+		})
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		data := result.StructuredContent.(DescribeTablesData)
+		assert.Equal(t, []string{"salaries"}, data.NotFound)
+		require.Len(t, data.Tables, 1)
+		assert.Equal(t, "orders", data.Tables[0].Name)
+	})
+
+	t.Run("analyze_column_impact", func(t *testing.T) {
+		result := call(contractor, handler.AnalyzeColumnImpact, map[string]interface{}{
+			"dbName": "app", "column": "users.id",
+		})
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "orders")
+		assert.NotContains(t, text, "salaries")
+	})
+
+	t.Run("diff_schemas", func(t *testing.T) {
+		result := call(contractor, handler.DiffSchemas, map[string]interface{}{
+			"sourceDbName": "app", "targetDbName": "payroll",
+		})
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "access denied to database payroll")
+	})
+
+	t.Run("views and routines referencing hidden tables", func(t *testing.T) {
+		objectNames := func(objects []SchemaObject, err error) []string {
+			t.Helper()
+			require.NoError(t, err)
+			var names []string
+			for _, o := range objects {
+				names = append(names, o.Name)
+			}
+			return names
+		}
+		assert.Equal(t, []string{"user_orders"}, objectNames(handler.db.FetchViews(contractor, "app")),
+			"views reading salaries or the payroll database are hidden, and aliases are not taken for databases")
+		assert.Equal(t, []string{"user_count"}, objectNames(handler.db.FetchRoutines(contractor, "app")))
+		assert.Equal(t, []string{"user_orders", "user_salaries", "user_payslips"}, objectNames(handler.db.FetchViews(admin, "app")))
+
+		for _, name := range []string{"user_salaries", "user_payslips", "raise_salary"} {
+			types, err := handler.db.FetchObjectTypes(contractor, "app", name)
+			require.NoError(t, err)
+			assert.Empty(t, types, "show_create can't read %s", name)
+		}
+		types, err := handler.db.FetchObjectTypes(contractor, "app", "user_count")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"FUNCTION"}, types)
+
+		snapshot, err := handler.access.visibleSnapshot(contractor, snapshots[0])
+		require.NoError(t, err)
+		assert.NotContains(t, objectNames(snapshot.Views, nil), "user_salaries")
+		assert.Equal(t, []string{"user_count"}, objectNames(snapshot.Routines, nil))
+	})
+
+	t.Run("no identity", func(t *testing.T) {
+		result := call(t.Context(), handler.ListTables, map[string]interface{}{"dbName": "app"})
+		assert.True(t, result.IsError, "callers without an identity only match *")
+	})
+}
+
+func TestObjectFilter(t *testing.T) {
+	filter := ObjectFilter{
+		AllowedDatabases: []string{"app", "/^shop_[0-9]+$/"},
+		DeniedDatabases:  []string{"shop_0"},
+		AllowedTables:    []string{"*"},
+		DeniedTables:     []string{"tmp_*", "/_(backup|old)$/", "app.secrets"},
+	}
+	require.NoError(t, filter.Validate())
+
+	assert.True(t, filter.databaseVisible("app"))
+	assert.True(t, filter.databaseVisible("SHOP_12"), "patterns ignore case")
+	assert.False(t, filter.databaseVisible("shop_0"), "denied patterns win")
+	assert.False(t, filter.databaseVisible("shop_x"))
+	assert.False(t, filter.databaseVisible("payroll"))
+
+	assert.True(t, filter.tableVisible("app", "orders"))
+	assert.False(t, filter.tableVisible("app", "tmp_import"))
+	assert.False(t, filter.tableVisible("app", "orders_backup"))
+	assert.True(t, filter.tableVisible("app", "backup_jobs"))
+	assert.False(t, filter.tableVisible("app", "secrets"))
+	assert.True(t, filter.tableVisible("shop_1", "secrets"), "qualified patterns only match their database")
+
+	assert.True(t, ObjectFilter{}.IsZero())
+	assert.Error(t, ObjectFilter{DeniedTables: []string{"/(/"}}.Validate())
+	assert.Error(t, ObjectFilter{AllowedDatabases: []string{"app["}}.Validate())
+}
+
+func TestSplitPatterns(t *testing.T) {
+	assert.Nil(t, splitPatterns(""))
+	assert.Equal(t, []string{"app", "shop_*"}, splitPatterns(" app, shop_* ,"))
+	assert.Equal(t, []string{"/^log_[0-9]{4,}$/", "tmp_*", "/a,b/"}, splitPatterns("/^log_[0-9]{4,}$/, tmp_*,/a,b/"))
+}
+
+func TestHandler_ObjectFilter(t *testing.T) {
+	snapshots, err := ParseDDL(`
+CREATE TABLE users (id INT PRIMARY KEY);
+CREATE TABLE user_secrets (id INT PRIMARY KEY);
+CREATE TABLE orders (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL,
+    secret_id INT,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_secret FOREIGN KEY (secret_id) REFERENCES user_secrets(id)
+);
+CREATE TABLE tmp_orders (id INT PRIMARY KEY);
+`, "app")
+	require.NoError(t, err)
+	db, err := NewSnapshotDB(snapshots)
+	require.NoError(t, err)
+	handler := NewHandler(db, "app", WithObjectFilter(ObjectFilter{DeniedTables: []string{"/secret/", "tmp_*"}}))
+
+	result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "secret_id -> (redacted)")
+	assert.Contains(t, text, "user_id -> users.id")
+	assert.NotContains(t, text, "user_secrets")
+	assert.NotContains(t, text, "tmp_orders")
+
+	result, err = handler.DescribeTables(t.Context(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Arguments: map[string]interface{}{"tableNames": []interface{}{"user_secrets"}}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "Table not found")
+
+	result, err = handler.TableDependencyOrder(t.Context(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"format": "json"}}})
+	require.NoError(t, err)
+	require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+	assert.Equal(t, []string{"users", "orders"}, result.StructuredContent.(DependencyOrderData).InsertionOrder)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// authConfig is the authentication configuration of the http transport
+type authConfig struct {
+	TokensFile string `yaml:"authTokensFile,omitempty"` // File of identity:token lines
+	Tokens     string `yaml:"authTokens,omitempty"`     // Comma-separated identity:token pairs
+	TLSCert    string `yaml:"tlsCert,omitempty"`        // Server certificate, which enables HTTPS
+	TLSKey     string `yaml:"tlsKey,omitempty"`
+	ClientCA   string `yaml:"tlsClientCA,omitempty"` // CA of the client certificates, which enables mTLS
+}
+
+// authenticator authenticates HTTP requests with bearer tokens or verified client certificates
+type authenticator struct {
+	tokens      map[string]string // token -> identity
+	clientCerts bool
+}
+
+type identityKey struct{}
+
+// withIdentity returns a context carrying the authenticated identity of the caller
+func withIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// identityFromContext returns the authenticated identity of the caller, which is empty without authentication (e.g. stdio)
+func identityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+	return identity
+}
+
+// loadHTTPAuth creates the authenticator and the TLS configuration of the http transport.
+// The authenticator is nil when neither tokens nor client certificates are configured,
+// and the TLS configuration is nil without a server certificate
+func loadHTTPAuth(config authConfig) (*authenticator, *tls.Config, error) {
+	tokens := map[string]string{}
+	if config.TokensFile != "" {
+		data, err := os.ReadFile(config.TokensFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the auth tokens file: %w", err)
+		}
+		if err := parseTokenEntries(tokens, strings.Split(string(data), "\n")); err != nil {
+			return nil, nil, fmt.Errorf("invalid auth tokens file %s: %w", config.TokensFile, err)
+		}
+	}
+	if config.Tokens != "" {
+		if err := parseTokenEntries(tokens, strings.Split(config.Tokens, ",")); err != nil {
+			return nil, nil, fmt.Errorf("invalid MCP_AUTH_TOKENS: %w", err)
+		}
+	}
+
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return nil, nil, fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	if config.ClientCA != "" && config.TLSCert == "" {
+		return nil, nil, fmt.Errorf("--tls-client-ca requires --tls-cert and --tls-key")
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load the TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+	if config.ClientCA != "" {
+		pem, err := os.ReadFile(config.ClientCA)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates in the client CA %s", config.ClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if len(tokens) > 0 {
+			// Clients without a certificate can still authenticate with a token
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	if len(tokens) == 0 && config.ClientCA == "" {
+		return nil, tlsConfig, nil
+	}
+	return &authenticator{tokens: tokens, clientCerts: config.ClientCA != ""}, tlsConfig, nil
+}
+
+// parseTokenEntries adds identity:token entries to tokens, skipping blank lines and # comments
+func parseTokenEntries(tokens map[string]string, entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		identity, token, ok := strings.Cut(entry, ":")
+		identity, token = strings.TrimSpace(identity), strings.TrimSpace(token)
+		if !ok || identity == "" || token == "" {
+			return fmt.Errorf("entries must be identity:token")
+		}
+		if other, exists := tokens[token]; exists {
+			return fmt.Errorf("identities %s and %s have the same token", other, identity)
+		}
+		tokens[token] = identity
+	}
+	return nil
+}
+
+// middleware rejects unauthenticated requests and passes the identity of the caller to the MCP server in the context
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := a.authenticate(r)
+		if !ok {
+			log.Printf("Rejected an unauthenticated request from %s", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mysql-schema-explorer-mcp"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+	})
+}
+
+// authenticate returns the identity of the bearer token, or of the verified client certificate when there is no token.
+// A wrong token is rejected even if the client certificate is valid
+func (a *authenticator) authenticate(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		return a.lookupToken(strings.TrimSpace(token))
+	}
+
+	if a.clientCerts && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		subject := r.TLS.VerifiedChains[0][0].Subject
+		if subject.CommonName != "" {
+			return subject.CommonName, true
+		}
+		return subject.String(), true
+	}
+	return "", false
+}
+
+// lookupToken compares the token with every configured token in constant time
+func (a *authenticator) lookupToken(token string) (string, bool) {
+	identity, found := "", false
+	for t, id := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			identity, found = id, true
+		}
+	}
+	return identity, found
+}
+
+// isLoopbackAddress reports whether the listen address only accepts connections from the local machine
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// logToolCall logs the identity of the caller and the tool on each tool call over an authenticated transport
+func logToolCall(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if identity := identityFromContext(ctx); identity != "" {
+			log.Printf("Tool call: identity=%s tool=%s", identity, request.Params.Name)
+		}
+		return next(ctx, request)
+	}
+}
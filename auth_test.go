@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadHTTPAuth(t *testing.T) {
+	tokensFile := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(tokensFile, []byte("# Team members\nalice:alice-token\n\nbob: bob-token \n"), 0o600))
+
+	t.Run("tokens", func(t *testing.T) {
+		auth, tlsConfig, err := loadHTTPAuth(authConfig{TokensFile: tokensFile, Tokens: "ci:ci-token"})
+		require.NoError(t, err)
+		assert.Nil(t, tlsConfig)
+		assert.Equal(t, map[string]string{"alice-token": "alice", "bob-token": "bob", "ci-token": "ci"}, auth.tokens)
+		assert.False(t, auth.clientCerts)
+	})
+
+	t.Run("no authentication", func(t *testing.T) {
+		auth, tlsConfig, err := loadHTTPAuth(authConfig{})
+		require.NoError(t, err)
+		assert.Nil(t, auth)
+		assert.Nil(t, tlsConfig)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			config   authConfig
+			errorMsg string
+		}{
+			{name: "missing file", config: authConfig{TokensFile: "testdata/missing"}, errorMsg: "failed to read the auth tokens file"},
+			{name: "no identity", config: authConfig{Tokens: "token-only"}, errorMsg: "invalid MCP_AUTH_TOKENS: entries must be identity:token"},
+			{name: "empty token", config: authConfig{Tokens: "alice:"}, errorMsg: "entries must be identity:token"},
+			{name: "shared token", config: authConfig{TokensFile: tokensFile, Tokens: "carol:alice-token"}, errorMsg: "identities alice and carol have the same token"},
+			{name: "cert without key", config: authConfig{TLSCert: "cert.pem"}, errorMsg: "--tls-cert and --tls-key must be set together"},
+			{name: "client CA without cert", config: authConfig{ClientCA: "ca.pem"}, errorMsg: "--tls-client-ca requires --tls-cert and --tls-key"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, _, err := loadHTTPAuth(tt.config)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			})
+		}
+	})
+}
+
+func TestAuthenticator(t *testing.T) {
+	auth := &authenticator{tokens: map[string]string{"alice-token": "alice"}, clientCerts: true}
+	var identity string
+	handler := auth.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = identityFromContext(r.Context())
+	}))
+
+	clientCert := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "deploy-bot"}}}}}
+
+	tests := []struct {
+		name     string
+		header   string
+		tls      *tls.ConnectionState
+		status   int
+		identity string
+	}{
+		{name: "token", header: "Bearer alice-token", status: http.StatusOK, identity: "alice"},
+		{name: "lowercase scheme", header: "bearer alice-token", status: http.StatusOK, identity: "alice"},
+		{name: "client certificate", tls: clientCert, status: http.StatusOK, identity: "deploy-bot"},
+		{name: "wrong token", header: "Bearer bob-token", status: http.StatusUnauthorized},
+		{name: "wrong token with a client certificate", header: "Bearer bob-token", tls: clientCert, status: http.StatusUnauthorized},
+		{name: "basic auth", header: "Basic YWxpY2U6YWxpY2UtdG9rZW4=", status: http.StatusUnauthorized},
+		{name: "no credentials", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity = ""
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			req.TLS = tt.tls
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.identity, identity)
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="mysql-schema-explorer-mcp"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestNewHTTPHandler_Authentication(t *testing.T) {
+	auth := &authenticator{tokens: map[string]string{"alice-token": "alice"}}
+	handler, _ := newHTTPHandler(newMCPServer(NewConnections(Connection{Name: defaultConnectionName, Handler: newTestMCPServerHandler(t)})), &http.Server{}, "/mcp", auth)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+	for _, path := range []string{"/mcp", "/mcp/message"} {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(initialize))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, path)
+	}
+
+	resp, err := http.Get(ts.URL + "/mcp/sse")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(initialize))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer alice-token")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	assert.True(t, isLoopbackAddress("localhost:8080"))
+	assert.True(t, isLoopbackAddress("127.0.0.1:8080"))
+	assert.True(t, isLoopbackAddress("[::1]:8080"))
+	assert.False(t, isLoopbackAddress(":8080"))
+	assert.False(t, isLoopbackAddress("0.0.0.0:8080"))
+	assert.False(t, isLoopbackAddress("10.0.0.5:8080"))
+}
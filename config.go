@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server. It is read from the config file, and the environment variables
+// override the settings of the file
+type Config struct {
+	Connections            []ConnectionConfig `yaml:"connections"`
+	Inference              InferenceConfig    `yaml:"inference"`
+	VirtualForeignKeysFile string             `yaml:"virtualForeignKeysFile,omitempty"`
+	Filters                ObjectFilter       `yaml:"filters"`
+	AccessPolicyFile       string             `yaml:"accessPolicyFile,omitempty"`
+	SnapshotDir            string             `yaml:"snapshotDir,omitempty"` // The directory of the snapshots that diff_schemas can compare with
+	Output                 OutputConfig       `yaml:"output"`
+	Transport              TransportConfig    `yaml:"transport"`
+}
+
+// OutputConfig is the default output settings of the tools
+type OutputConfig struct {
+	ListTablesMaxTokens int    `yaml:"listTablesMaxTokens"` // Default output limit of list_tables (0 means unlimited)
+	TemplateDir         string `yaml:"templateDir,omitempty"`
+}
+
+// TransportConfig is the transport of the MCP server
+type TransportConfig struct {
+	Type       string `yaml:"type"` // stdio or http
+	Listen     string `yaml:"listen"`
+	BasePath   string `yaml:"basePath"`
+	authConfig `yaml:",inline"`
+}
+
+// defaultConfig returns the configuration used for the settings that are neither in the file nor in the environment
+func defaultConfig() Config {
+	return Config{
+		Inference: InferenceConfig{MinConfidence: defaultInferMinConfidence},
+		Transport: TransportConfig{
+			Type:     transportStdio,
+			Listen:   defaultListenAddress,
+			BasePath: defaultBasePath,
+		},
+	}
+}
+
+// readConfig reads the config file (YAML or JSON) such as the following, if any, and applies the environment
+// variables over it. The result is not validated yet:
+//
+//	connections:
+//	  - name: primary
+//	    host: db-primary.internal
+//	    user: readonly
+//	filters:
+//	  deniedDatabases: [mysql, sys]
+//	output:
+//	  listTablesMaxTokens: 20000
+//	transport:
+//	  type: http
+//	  listen: localhost:8080
+func readConfig(filename string) (Config, error) {
+	config := defaultConfig()
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return Config{}, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// loadConfig reads and validates the configuration
+func loadConfig(filename string) (Config, error) {
+	config, err := readConfig(filename)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// applyEnv overrides the settings with the environment variables that are set
+func (c *Config) applyEnv() error {
+	if path := os.Getenv("CONNECTIONS_FILE"); path != "" {
+		connections, err := LoadConnectionConfigs(path)
+		if err != nil {
+			return fmt.Errorf("failed to load connections: %w", err)
+		}
+		c.Connections = connections
+	}
+
+	// DB_* and SCHEMA_SNAPSHOT configure the default connection, which is the first one
+	if len(c.Connections) == 0 && (os.Getenv("DB_USER") != "" || os.Getenv("DB_DSN") != "" || os.Getenv("DB_OPTION_FILE") != "" || os.Getenv("SCHEMA_SNAPSHOT") != "") {
+		c.Connections = []ConnectionConfig{{Name: defaultConnectionName}}
+	}
+	if len(c.Connections) > 0 {
+		first := &c.Connections[0]
+		overrideString(&first.DSN, os.Getenv("DB_DSN"))
+		overrideString(&first.Host, os.Getenv("DB_HOST"))
+		overrideString(&first.Port, os.Getenv("DB_PORT"))
+		overrideString(&first.Socket, os.Getenv("DB_SOCKET"))
+		overrideString(&first.User, os.Getenv("DB_USER"))
+		if password, file, command := os.Getenv("DB_PASSWORD"), os.Getenv("DB_PASSWORD_FILE"), os.Getenv("DB_PASSWORD_COMMAND"); password != "" || file != "" || command != "" {
+			// A password source in the environment replaces the one in the file
+			first.Password, first.PasswordFile, first.PasswordCommand = password, file, command
+		}
+		overrideString(&first.OptionFile, os.Getenv("DB_OPTION_FILE"))
+		overrideString(&first.TLS, os.Getenv("DB_TLS"))
+		overrideString(&first.TLSCA, os.Getenv("DB_TLS_CA"))
+		overrideString(&first.TLSCert, os.Getenv("DB_TLS_CERT"))
+		overrideString(&first.TLSKey, os.Getenv("DB_TLS_KEY"))
+		overrideString(&first.ServerPublicKey, os.Getenv("DB_SERVER_PUBLIC_KEY"))
+		if err := overrideDuration(&first.ConnectTimeout, "DB_CONNECT_TIMEOUT"); err != nil {
+			return err
+		}
+		if err := overrideDuration(&first.ReadTimeout, "DB_READ_TIMEOUT"); err != nil {
+			return err
+		}
+		overrideString(&first.Charset, os.Getenv("DB_CHARSET"))
+		overrideString(&first.TimeZone, os.Getenv("DB_TIME_ZONE"))
+		overrideString(&first.Database, os.Getenv("DB_NAME"))
+		overrideString(&first.Snapshot, os.Getenv("SCHEMA_SNAPSHOT"))
+	}
+
+	if v := os.Getenv("INFER_FOREIGN_KEYS"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("INFER_FOREIGN_KEYS must be a boolean: %w", err)
+		}
+		c.Inference.Enabled = enabled
+	}
+	if v := os.Getenv("INFER_MIN_CONFIDENCE"); v != "" {
+		minConfidence, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("INFER_MIN_CONFIDENCE must be a number between 0 and 1: %s", v)
+		}
+		c.Inference.MinConfidence = minConfidence
+	}
+	overrideString(&c.VirtualForeignKeysFile, os.Getenv("VIRTUAL_FOREIGN_KEYS_FILE"))
+
+	overridePatterns(&c.Filters.AllowedDatabases, os.Getenv("ALLOWED_DATABASES"))
+	overridePatterns(&c.Filters.DeniedDatabases, os.Getenv("DENIED_DATABASES"))
+	overridePatterns(&c.Filters.AllowedTables, os.Getenv("ALLOWED_TABLES"))
+	overridePatterns(&c.Filters.DeniedTables, os.Getenv("DENIED_TABLES"))
+	overrideString(&c.AccessPolicyFile, os.Getenv("ACCESS_POLICY_FILE"))
+	overrideString(&c.SnapshotDir, os.Getenv("SNAPSHOT_DIR"))
+
+	if v := os.Getenv("LIST_TABLES_MAX_TOKENS"); v != "" {
+		maxTokens, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("LIST_TABLES_MAX_TOKENS must be a non-negative integer: %s", v)
+		}
+		c.Output.ListTablesMaxTokens = maxTokens
+	}
+	overrideString(&c.Output.TemplateDir, os.Getenv("TEMPLATE_DIR"))
+
+	overrideString(&c.Transport.Type, os.Getenv("MCP_TRANSPORT"))
+	overrideString(&c.Transport.Listen, os.Getenv("MCP_LISTEN"))
+	overrideString(&c.Transport.BasePath, os.Getenv("MCP_BASE_PATH"))
+	overrideString(&c.Transport.Tokens, os.Getenv("MCP_AUTH_TOKENS"))
+	overrideString(&c.Transport.TokensFile, os.Getenv("MCP_AUTH_TOKENS_FILE"))
+	overrideString(&c.Transport.TLSCert, os.Getenv("MCP_TLS_CERT"))
+	overrideString(&c.Transport.TLSKey, os.Getenv("MCP_TLS_KEY"))
+	overrideString(&c.Transport.ClientCA, os.Getenv("MCP_TLS_CLIENT_CA"))
+	return nil
+}
+
+// overrideString replaces the setting with the value of an environment variable, unless it is empty
+func overrideString(setting *string, value string) {
+	if value != "" {
+		*setting = value
+	}
+}
+
+// overrideDuration replaces the setting with the duration in an environment variable, unless it is empty
+func overrideDuration(setting *time.Duration, key string) error {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s must be a duration such as 10s: %s", key, v)
+	}
+	*setting = d
+	return nil
+}
+
+// overridePatterns replaces the patterns with a comma-separated list from an environment variable, unless it is empty
+func overridePatterns(setting *[]string, value string) {
+	if patterns := splitPatterns(value); patterns != nil {
+		*setting = patterns
+	}
+}
+
+// Validate checks the configuration and fills in the defaults of the connections
+func (c *Config) Validate() error {
+	if len(c.Connections) == 0 {
+		return fmt.Errorf("DB_USER environment variable is not set (or set DB_DSN, DB_OPTION_FILE, SCHEMA_SNAPSHOT, CONNECTIONS_FILE or connections in the config file)")
+	}
+	if err := validateConnections(c.Connections); err != nil {
+		return err
+	}
+
+	if c.Inference.MinConfidence < 0 || c.Inference.MinConfidence > 1 {
+		return fmt.Errorf("inference.minConfidence (INFER_MIN_CONFIDENCE) must be a number between 0 and 1: %v", c.Inference.MinConfidence)
+	}
+	if err := c.Filters.Validate(); err != nil {
+		return fmt.Errorf("filters: %w", err)
+	}
+	if c.Output.ListTablesMaxTokens < 0 {
+		return fmt.Errorf("output.listTablesMaxTokens (LIST_TABLES_MAX_TOKENS) must be a non-negative integer: %d", c.Output.ListTablesMaxTokens)
+	}
+	if c.Transport.Type != transportStdio && c.Transport.Type != transportHTTP {
+		return fmt.Errorf("transport.type (--transport, MCP_TRANSPORT) must be %q or %q", transportStdio, transportHTTP)
+	}
+	return nil
+}
+
+// connection returns the settings of the named connection, or of the default connection when name is empty
+func (c Config) connection(name string) (ConnectionConfig, bool) {
+	for i, conn := range c.Connections {
+		if conn.Name == name || (name == "" && i == 0) {
+			return conn, true
+		}
+	}
+	return ConnectionConfig{}, false
+}
+
+// maskedSecret replaces passwords and tokens in the output of config check
+const maskedSecret = "********"
+
+// masked returns a copy of the configuration with the secrets masked
+func (c Config) masked() Config {
+	c.Connections = append([]ConnectionConfig(nil), c.Connections...)
+	for i := range c.Connections {
+		if c.Connections[i].Password != "" {
+			c.Connections[i].Password = maskedSecret
+		}
+		if c.Connections[i].DSN != "" {
+			c.Connections[i].DSN = maskDSN(c.Connections[i].DSN)
+		}
+	}
+	if c.Transport.Tokens != "" {
+		c.Transport.Tokens = maskedSecret
+	}
+	return c
+}
+
+// runConfig implements the config subcommand. "config check" validates the configuration, including the files it
+// refers to, and prints the effective configuration with the secrets masked:
+//
+//	mysql-schema-explorer-mcp config check --config config.yaml
+func runConfig(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: mysql-schema-explorer-mcp config check [--config file]")
+	}
+
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "The config file (default: CONFIG_FILE)")
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if _, err := loadHandlerOptions(config); err != nil {
+		return err
+	}
+	if config.Transport.Type == transportHTTP {
+		if _, _, err := loadHTTPAuth(config.Transport.authConfig); err != nil {
+			return err
+		}
+	}
+
+	encoder := yaml.NewEncoder(stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config.masked()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
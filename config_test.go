@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearConfigEnv unsets the environment variables that override the config file
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"CONNECTIONS_FILE", "DB_DSN", "DB_HOST", "DB_PORT", "DB_SOCKET", "DB_USER", "DB_PASSWORD", "DB_PASSWORD_FILE", "DB_PASSWORD_COMMAND", "DB_OPTION_FILE", "DB_NAME", "SCHEMA_SNAPSHOT",
+		"DB_TLS", "DB_TLS_CA", "DB_TLS_CERT", "DB_TLS_KEY", "DB_SERVER_PUBLIC_KEY",
+		"DB_CONNECT_TIMEOUT", "DB_READ_TIMEOUT", "DB_CHARSET", "DB_TIME_ZONE",
+		"INFER_FOREIGN_KEYS", "INFER_MIN_CONFIDENCE", "VIRTUAL_FOREIGN_KEYS_FILE",
+		"ALLOWED_DATABASES", "DENIED_DATABASES", "ALLOWED_TABLES", "DENIED_TABLES", "ACCESS_POLICY_FILE", "SNAPSHOT_DIR",
+		"LIST_TABLES_MAX_TOKENS", "TEMPLATE_DIR",
+		"MCP_TRANSPORT", "MCP_LISTEN", "MCP_BASE_PATH", "MCP_AUTH_TOKENS", "MCP_AUTH_TOKENS_FILE",
+		"MCP_TLS_CERT", "MCP_TLS_KEY", "MCP_TLS_CLIENT_CA",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	clearConfigEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+connections:
+  - name: primary
+    host: db-primary.internal
+    user: readonly
+    password: secret
+  - name: legacy
+    snapshot: schema/legacy.sql
+inference:
+  enabled: true
+filters:
+  deniedDatabases: [mysql, sys]
+  deniedTables: [tmp_*]
+output:
+  listTablesMaxTokens: 20000
+transport:
+  type: http
+  authTokensFile: tokens
+`), 0o644))
+
+	t.Run("file", func(t *testing.T) {
+		config, err := loadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, Config{
+			Connections: []ConnectionConfig{
+				{Name: "primary", DBConfig: DBConfig{Host: "db-primary.internal", Port: "3306", User: "readonly", Password: "secret"}},
+				{Name: "legacy", Snapshot: "schema/legacy.sql"},
+			},
+			Inference: InferenceConfig{Enabled: true, MinConfidence: defaultInferMinConfidence},
+			Filters:   ObjectFilter{DeniedDatabases: []string{"mysql", "sys"}, DeniedTables: []string{"tmp_*"}},
+			Output:    OutputConfig{ListTablesMaxTokens: 20000},
+			Transport: TransportConfig{
+				Type:       transportHTTP,
+				Listen:     defaultListenAddress,
+				BasePath:   defaultBasePath,
+				authConfig: authConfig{TokensFile: "tokens"},
+			},
+		}, config)
+	})
+
+	t.Run("environment variables override the file", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "from-env")
+		t.Setenv("DB_NAME", "app")
+		t.Setenv("DENIED_TABLES", "audit_*")
+		t.Setenv("LIST_TABLES_MAX_TOKENS", "500")
+		t.Setenv("MCP_TRANSPORT", transportStdio)
+
+		config, err := loadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, ConnectionConfig{Name: "primary", DBConfig: DBConfig{Host: "db-primary.internal", Port: "3306", User: "readonly", Password: "from-env"}, Database: "app"},
+			config.Connections[0], "DB_* override the first connection")
+		assert.Equal(t, ConnectionConfig{Name: "legacy", Snapshot: "schema/legacy.sql"}, config.Connections[1])
+		assert.Equal(t, ObjectFilter{DeniedDatabases: []string{"mysql", "sys"}, DeniedTables: []string{"audit_*"}}, config.Filters)
+		assert.Equal(t, 500, config.Output.ListTablesMaxTokens)
+		assert.Equal(t, transportStdio, config.Transport.Type)
+	})
+
+	t.Run("password source in environment variables replaces the file password", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD_FILE", "/run/secrets/db_password")
+
+		config, err := loadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, DBConfig{Host: "db-primary.internal", Port: "3306", User: "readonly", PasswordFile: "/run/secrets/db_password"}, config.Connections[0].DBConfig)
+	})
+
+	t.Run("environment variables only", func(t *testing.T) {
+		t.Setenv("DB_USER", "root")
+		t.Setenv("DB_PORT", "13306")
+
+		config, err := loadConfig("")
+		require.NoError(t, err)
+		assert.Equal(t, []ConnectionConfig{{Name: defaultConnectionName, DBConfig: DBConfig{Host: "localhost", Port: "13306", User: "root"}}}, config.Connections)
+		assert.Equal(t, defaultConfig().Transport, config.Transport)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() Config {
+		config := defaultConfig()
+		config.Connections = []ConnectionConfig{{Name: defaultConnectionName, DBConfig: DBConfig{User: "root"}}}
+		return config
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*Config)
+		errorMsg string
+	}{
+		{name: "no connections", modify: func(c *Config) { c.Connections = nil }, errorMsg: "DB_USER environment variable is not set"},
+		{name: "no user", modify: func(c *Config) { c.Connections[0].User = "" }, errorMsg: "connections[0]: user, dsn, optionFile or snapshot is required"},
+		{name: "min confidence", modify: func(c *Config) { c.Inference.MinConfidence = 1.5 }, errorMsg: "inference.minConfidence (INFER_MIN_CONFIDENCE) must be a number between 0 and 1"},
+		{name: "filter pattern", modify: func(c *Config) { c.Filters.DeniedTables = []string{"/(/"} }, errorMsg: "filters: "},
+		{name: "max tokens", modify: func(c *Config) { c.Output.ListTablesMaxTokens = -1 }, errorMsg: "output.listTablesMaxTokens (LIST_TABLES_MAX_TOKENS) must be a non-negative integer"},
+		{name: "transport", modify: func(c *Config) { c.Transport.Type = "grpc" }, errorMsg: "transport.type (--transport, MCP_TRANSPORT) must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(&config)
+			err := config.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		clearConfigEnv(t)
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("output:\n  maxTokens: 100\n"), 0o644))
+		_, err := readConfig(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "field maxTokens not found")
+	})
+}
+
+func TestConfig_Masked(t *testing.T) {
+	config := defaultConfig()
+	config.Connections = []ConnectionConfig{{Name: "primary", DBConfig: DBConfig{User: "root", Password: "secret"}}, {Name: "legacy", Snapshot: "schema.sql"}}
+	config.Transport.Tokens = "alice:alice-token"
+
+	masked := config.masked()
+	assert.Equal(t, maskedSecret, masked.Connections[0].Password)
+	assert.Empty(t, masked.Connections[1].Password, "empty secrets stay empty")
+	assert.Equal(t, maskedSecret, masked.Transport.Tokens)
+	assert.Equal(t, "secret", config.Connections[0].Password, "the original is not modified")
+}
+
+func TestRunConfig(t *testing.T) {
+	clearConfigEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+connections:
+  - name: primary
+    user: readonly
+    password: secret
+`), 0o644))
+
+	var output bytes.Buffer
+	require.NoError(t, runConfig([]string{"check", "--config", path}, &output))
+	assert.Contains(t, output.String(), "name: primary")
+	assert.Contains(t, output.String(), "password: '"+maskedSecret+"'")
+	assert.NotContains(t, output.String(), "secret")
+
+	output.Reset()
+	assert.ErrorContains(t, runConfig([]string{"validate"}, &output), "usage:")
+	assert.ErrorContains(t, runConfig([]string{"check", "--config", filepath.Join(t.TempDir(), "missing.yaml")}, &output), "missing.yaml")
+	assert.Empty(t, output.String(), "nothing is written for an invalid configuration")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// ConnectionConfig is a named database connection in the connections file
+type ConnectionConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	DBConfig    `yaml:",inline"`
+	Database    string `yaml:"database,omitempty"` // Fixes the database of the connection like DB_NAME
+	Snapshot    string `yaml:"snapshot,omitempty"` // Serves a schema snapshot like SCHEMA_SNAPSHOT instead of connecting
+}
+
+// connectionsFile is the structure of the connections file (YAML or JSON)
+type connectionsFile struct {
+	Connections []ConnectionConfig `yaml:"connections"`
+}
+
+// LoadConnectionConfigs loads the connections from a YAML or JSON file such as:
+//
+//	connections:
+//	  - name: primary
+//	    description: Primary MySQL
+//	    host: db-primary.internal
+//	    user: readonly
+//	    password: secret
+//	    database: app
+//	  - name: legacy
+//	    snapshot: schema/legacy.sql
+func LoadConnectionConfigs(filename string) ([]ConnectionConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var file connectionsFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	if len(file.Connections) == 0 {
+		return nil, fmt.Errorf("%s: no connections", filename)
+	}
+	if err := validateConnections(file.Connections); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return file.Connections, nil
+}
+
+// validateConnections checks the connections and fills in the default host and port
+func validateConnections(configs []ConnectionConfig) error {
+	names := map[string]bool{}
+	for i := range configs {
+		c := &configs[i]
+		switch {
+		case c.Name == "":
+			return fmt.Errorf("connections[%d]: name is required", i)
+		case names[c.Name]:
+			return fmt.Errorf("connections[%d]: duplicate name %s", i, c.Name)
+		case c.Snapshot == "" && c.User == "" && c.DSN == "" && c.OptionFile == "":
+			return fmt.Errorf("connections[%d]: user, dsn, optionFile or snapshot is required", i)
+		}
+		names[c.Name] = true
+		if c.Snapshot != "" {
+			continue
+		}
+		if err := c.DBConfig.Validate(); err != nil {
+			return fmt.Errorf("connections[%d]: %w", i, err)
+		}
+		if c.DSN != "" || c.Socket != "" || c.OptionFile != "" {
+			// The DSN, the socket or the option file has the address
+			continue
+		}
+		if c.Host == "" {
+			c.Host = "localhost"
+		}
+		if c.Port == "" {
+			c.Port = "3306"
+		}
+	}
+	return nil
+}
+
+// openConnection serves the snapshot of the connection, or connects to its MySQL server
+func openConnection(config ConnectionConfig) (DB, func(), error) {
+	if config.Snapshot != "" {
+		return openSnapshotDB(config.Snapshot)
+	}
+	return openMySQLDB(config.DBConfig)
+}
+
+// defaultConnectionName is the name of the connection configured by environment variables
+const defaultConnectionName = "default"
+
+// Connection is a named database connection served by its own handler, with its own pool and fixed database
+type Connection struct {
+	Name        string
+	Description string
+	Handler     *Handler
+}
+
+// Connections routes tool calls to the handler of the connection named by the connection argument.
+// The first connection is the default
+type Connections struct {
+	list []Connection
+}
+
+func NewConnections(list ...Connection) *Connections {
+	return &Connections{list: list}
+}
+
+// Multiple reports whether there is more than one connection, in which case the tools take a connection argument
+func (c *Connections) Multiple() bool {
+	return len(c.list) > 1
+}
+
+// Names returns the names of the connections
+func (c *Connections) Names() []string {
+	names := make([]string, len(c.list))
+	for i, conn := range c.list {
+		names[i] = conn.Name
+	}
+	return names
+}
+
+// fixedDatabases returns the number of connections with a fixed database
+func (c *Connections) fixedDatabases() int {
+	fixed := 0
+	for _, conn := range c.list {
+		if conn.Handler.fixedDBName != "" {
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// Handler returns the handler of the connection, or of the default connection when name is empty
+func (c *Connections) Handler(name string) (*Handler, error) {
+	if name == "" {
+		return c.list[0].Handler, nil
+	}
+	for _, conn := range c.list {
+		if conn.Name == name {
+			return conn.Handler, nil
+		}
+	}
+	return nil, fmt.Errorf("connection %s does not exist (available: %s)", name, strings.Join(c.Names(), ", "))
+}
+
+// route returns a tool handler that calls the tool on the connection of the request
+func (c *Connections) route(tool func(*Handler, context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		handler, err := c.Handler(request.GetString("connection", ""))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return tool(handler, ctx, request)
+	}
+}
+
+// ConnectionInfo describes a connection for list_connections
+type ConnectionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Database    string `json:"database,omitempty"` // The fixed database, if any
+	Default     bool   `json:"default"`
+}
+
+// ListConnections returns the connections that the tools can use, leaving out the connections
+// on which the caller can see nothing and those whose fixed database the caller cannot see
+func (c *Connections) ListConnections(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	format, err := getFormat(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data := ListConnectionsData{Connections: []ConnectionInfo{}}
+	for i, conn := range c.list {
+		access := conn.Handler.access
+		if !access.connectionVisible(ctx) {
+			continue
+		}
+		if fixed := conn.Handler.fixedDBName; fixed != "" && !access.databaseVisible(ctx, fixed) {
+			continue
+		}
+		data.Connections = append(data.Connections, ConnectionInfo{
+			Name:        conn.Name,
+			Description: conn.Description,
+			Database:    conn.Handler.fixedDBName,
+			Default:     i == 0,
+		})
+	}
+	if format == formatJSON {
+		return newJSONResult(data)
+	}
+
+	// Every connection shares the templates
+	var output bytes.Buffer
+	if err := c.list[0].Handler.templates.ListConnections.Execute(&output, data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+	}
+	return mcp.NewToolResultText(output.String()), nil
+}
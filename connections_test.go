@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConnectionConfigs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "connections.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	configs, err := LoadConnectionConfigs(write(`
+connections:
+  - name: primary
+    description: Primary MySQL
+    host: db-primary.internal
+    port: 3307
+    user: readonly
+    password: secret
+    database: app
+  - name: analytics
+    user: analyst
+  - name: legacy
+    snapshot: schema/legacy.sql
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []ConnectionConfig{
+		{Name: "primary", Description: "Primary MySQL", DBConfig: DBConfig{Host: "db-primary.internal", Port: "3307", User: "readonly", Password: "secret"}, Database: "app"},
+		{Name: "analytics", DBConfig: DBConfig{Host: "localhost", Port: "3306", User: "analyst"}},
+		{Name: "legacy", Snapshot: "schema/legacy.sql"},
+	}, configs)
+
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+	}{
+		{name: "no connections", content: "connections: []", errorMsg: "no connections"},
+		{name: "no name", content: "connections:\n  - user: readonly", errorMsg: "connections[0]: name is required"},
+		{name: "duplicate name", content: "connections:\n  - {name: a, user: u}\n  - {name: a, user: u}", errorMsg: "connections[1]: duplicate name a"},
+		{name: "no user", content: "connections:\n  - name: a", errorMsg: "connections[0]: user, dsn, optionFile or snapshot is required"},
+		{name: "unknown field", content: "connections:\n  - name: a\n    username: u", errorMsg: "field username not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConnectionConfigs(write(tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestConnections(t *testing.T) {
+	newHandler := func(ddl, fixedDBName string, opts ...HandlerOption) *Handler {
+		snapshots, err := ParseDDL(ddl, "app")
+		require.NoError(t, err)
+		db, err := NewSnapshotDB(snapshots)
+		require.NoError(t, err)
+		return NewHandler(db, fixedDBName, opts...)
+	}
+	connections := NewConnections(
+		Connection{Name: "primary", Description: "Primary MySQL", Handler: newHandler("CREATE TABLE users (id INT PRIMARY KEY);", "app")},
+		Connection{Name: "legacy", Handler: newHandler("CREATE TABLE customers (id INT PRIMARY KEY);", "")},
+	)
+	listTables := connections.route((*Handler).ListTables)
+
+	t.Run("route", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			args  map[string]interface{}
+			table string
+		}{
+			{name: "default connection", args: map[string]interface{}{}, table: "users"},
+			{name: "named connection", args: map[string]interface{}{"connection": "primary"}, table: "users"},
+			{name: "other connection", args: map[string]interface{}{"connection": "legacy", "dbName": "app"}, table: "customers"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := listTables(t.Context(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: tt.args}})
+				require.NoError(t, err)
+				require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+				assert.Contains(t, result.Content[0].(mcp.TextContent).Text, tt.table)
+			})
+		}
+	})
+
+	t.Run("fixed database per connection", func(t *testing.T) {
+		result, err := listTables(t.Context(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"connection": "legacy"}}})
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "legacy has no fixed database")
+	})
+
+	t.Run("unknown connection", func(t *testing.T) {
+		result, err := listTables(t.Context(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"connection": "replica"}}})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "connection replica does not exist (available: primary, legacy)", result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("list_connections", func(t *testing.T) {
+		result, err := connections.ListConnections(t.Context(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		assert.Equal(t, "Connections (Total: 2)\n- primary (default) - Primary MySQL [database: app]\n- legacy\n", result.Content[0].(mcp.TextContent).Text)
+
+		result, err = connections.ListConnections(t.Context(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"format": "json"}}})
+		require.NoError(t, err)
+		assert.Equal(t, ListConnectionsData{Connections: []ConnectionInfo{
+			{Name: "primary", Description: "Primary MySQL", Database: "app", Default: true},
+			{Name: "legacy"},
+		}}, result.StructuredContent)
+	})
+
+	t.Run("list_connections with access policies", func(t *testing.T) {
+		policies := WithAccessPolicies([]AccessPolicy{
+			{Identities: []string{"alice"}, Connections: []string{"legacy"}, Databases: []string{"app"}},
+			{Identities: []string{"bob"}, Databases: []string{"other"}},
+		})
+		connections := NewConnections(
+			Connection{Name: "primary", Handler: newHandler("CREATE TABLE users (id INT PRIMARY KEY);", "app", policies, WithConnectionName("primary"))},
+			Connection{Name: "legacy", Handler: newHandler("CREATE TABLE customers (id INT PRIMARY KEY);", "", policies, WithConnectionName("legacy"))},
+		)
+		listConnections := func(ctx context.Context) []string {
+			t.Helper()
+			result, err := connections.ListConnections(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"format": "json"}}})
+			require.NoError(t, err)
+			var names []string
+			for _, conn := range result.StructuredContent.(ListConnectionsData).Connections {
+				names = append(names, conn.Name)
+			}
+			return names
+		}
+
+		// alice's policy is scoped to legacy, and bob cannot see app, the fixed database of primary
+		assert.Equal(t, []string{"legacy"}, listConnections(withIdentity(t.Context(), "alice")))
+		assert.Equal(t, []string{"legacy"}, listConnections(withIdentity(t.Context(), "bob")))
+		assert.Empty(t, listConnections(t.Context()))
+
+		result, err := connections.route((*Handler).ListTables)(withIdentity(t.Context(), "alice"), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{"connection": "primary"}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "access denied to database app", result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("tools", func(t *testing.T) {
+		listTools := func(connections *Connections) map[string]mcp.Tool {
+			t.Helper()
+			response := newMCPServer(connections).HandleMessage(t.Context(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+			data, err := json.Marshal(response)
+			require.NoError(t, err)
+			var list struct {
+				Result mcp.ListToolsResult `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal(data, &list))
+			tools := map[string]mcp.Tool{}
+			for _, tool := range list.Result.Tools {
+				tools[tool.Name] = tool
+			}
+			return tools
+		}
+
+		tools := listTools(connections)
+		require.Contains(t, tools, "list_connections")
+		for name, tool := range tools {
+			if name == "list_connections" {
+				continue
+			}
+			assert.Contains(t, tool.InputSchema.Properties, "connection", name)
+			assert.NotContains(t, tool.InputSchema.Required, "dbName", "dbName is optional when some connections have a fixed database")
+		}
+
+		tools = listTools(NewConnections(Connection{Name: defaultConnectionName, Handler: newTestMCPServerHandler(t)}))
+		assert.Len(t, tools, 6, "a single connection has no list_connections tool")
+		for name, tool := range tools {
+			assert.NotContains(t, tool.InputSchema.Properties, "connection", name)
+		}
+
+		tools = listTools(NewConnections(Connection{Name: defaultConnectionName, Handler: newHandler("CREATE TABLE users (id INT PRIMARY KEY);", "app")}))
+		assert.NotContains(t, tools, "diff_schemas", "a fixed database has nothing to be compared with")
+		tools = listTools(NewConnections(Connection{Name: defaultConnectionName, Handler: newHandler("CREATE TABLE users (id INT PRIMARY KEY);", "app", WithSnapshotDir(t.TempDir()))}))
+		assert.Contains(t, tools, "diff_schemas", "a fixed database can be compared with a snapshot")
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// passwordCommandTimeout is how long the password command may take
+const passwordCommandTimeout = 30 * time.Second
+
+// hasPasswordSource reports whether the password is read from a file or a command on each connection
+func (c DBConfig) hasPasswordSource() bool {
+	return c.PasswordFile != "" || c.PasswordCommand != ""
+}
+
+// password reads PasswordFile or runs PasswordCommand. It is called for every new connection, so that rotated
+// passwords are picked up when the pool reconnects
+func (c DBConfig) password(ctx context.Context) (string, error) {
+	if c.PasswordFile != "" {
+		data, err := os.ReadFile(expandHome(c.PasswordFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read the password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, passwordCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.PasswordCommand)
+	// Standard output is the password, and must never reach the stdio transport
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run the password command: %w", err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// passwordBeforeConnect sets the password of each new connection from PasswordFile or PasswordCommand
+func (c DBConfig) passwordBeforeConnect() mysql.Option {
+	return mysql.BeforeConnect(func(ctx context.Context, cfg *mysql.Config) error {
+		password, err := c.password(ctx)
+		if err != nil {
+			return err
+		}
+		cfg.Passwd = password
+		return nil
+	})
+}
+
+// withOptionFile fills in the settings that are set neither directly nor by DSN from the [client] section of OptionFile
+func (c DBConfig) withOptionFile() (DBConfig, error) {
+	if c.OptionFile == "" {
+		return c, nil
+	}
+	var dsn dsnSettings
+	if c.DSN != "" {
+		var err error
+		if dsn, err = parseDSNSettings(c.DSN); err != nil {
+			return DBConfig{}, err
+		}
+	}
+	options, err := readOptionFile(c.OptionFile)
+	if err != nil {
+		return DBConfig{}, err
+	}
+
+	fill := func(setting *string, key string) {
+		if *setting == "" {
+			*setting = options[key]
+		}
+	}
+	if !dsn.user {
+		fill(&c.User, "user")
+	}
+	if c.Password == "" && !c.hasPasswordSource() && !dsn.password {
+		c.Password = options["password"]
+	}
+	if c.Host == "" && c.Port == "" && c.Socket == "" && !dsn.address {
+		fill(&c.Host, "host")
+		fill(&c.Port, "port")
+		fill(&c.Socket, "socket")
+	}
+	fill(&c.TLSCA, "ssl-ca")
+	fill(&c.TLSCert, "ssl-cert")
+	fill(&c.TLSKey, "ssl-key")
+	return c, nil
+}
+
+// dsnSettings tells which of the settings that an option file can fill in are set by a DSN
+type dsnSettings struct {
+	user     bool
+	password bool
+	address  bool
+}
+
+// parseDSNSettings parses the DSN and reports which settings it sets. The address is set by "net(addr)",
+// which ParseDSN can't tell from the default address
+func parseDSNSettings(dsn string) (dsnSettings, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return dsnSettings{}, fmt.Errorf("invalid dsn: %w", err)
+	}
+	// [user[:password]@][net[(addr)]]/dbname[?params], where the password may contain '@' and '/'
+	head := dsn[:strings.LastIndex(dsn, "/")]
+	netAddr := head[strings.LastIndex(head, "@")+1:]
+	_, addr, _ := strings.Cut(netAddr, "(")
+	return dsnSettings{
+		user:     cfg.User != "",
+		password: cfg.Passwd != "",
+		address:  strings.TrimSuffix(addr, ")") != "",
+	}, nil
+}
+
+// readOptionFile reads the [client] section of a MySQL option file such as ~/.my.cnf:
+//
+//	[client]
+//	user = readonly
+//	password = "secret"
+func readOptionFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the option file: %w", err)
+	}
+
+	options := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!':
+			// Comments, and directives such as !include
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		case section != "client":
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.TrimSpace(key), "_", "-")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		options[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the option file: %w", err)
+	}
+	return options, nil
+}
+
+// expandHome expands a leading ~/ to the home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
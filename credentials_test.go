@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBConfig_Password(t *testing.T) {
+	t.Run("password file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "password.txt")
+		require.NoError(t, os.WriteFile(path, []byte("s3cret \n"), 0o600))
+		password, err := DBConfig{PasswordFile: path}.password(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret ", password, "only the trailing newline is trimmed")
+
+		require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o600))
+		password, err = DBConfig{PasswordFile: path}.password(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", password, "the file is read again")
+
+		_, err = DBConfig{PasswordFile: filepath.Join(t.TempDir(), "missing.txt")}.password(t.Context())
+		assert.ErrorContains(t, err, "failed to read the password file")
+	})
+
+	t.Run("password command", func(t *testing.T) {
+		password, err := DBConfig{PasswordCommand: "echo token-$((1 + 1))"}.password(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "token-2", password)
+
+		_, err = DBConfig{PasswordCommand: "exit 1"}.password(t.Context())
+		assert.ErrorContains(t, err, "failed to run the password command")
+	})
+
+	t.Run("mysql config", func(t *testing.T) {
+		cfg, err := DBConfig{DSN: "app:secret@tcp(db.internal:3306)/", PasswordCommand: "echo token"}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "secret", cfg.Passwd, "the command sets the password when connecting")
+	})
+}
+
+func TestDBConfig_WithOptionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my.cnf")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# Options of the mysql client
+[mysqld]
+user = mysql
+
+[client]
+user = readonly
+password = "p#ss word"
+host = db.internal
+port = 3307
+ssl_ca = /etc/mysql/ca.pem
+!includedir /etc/mysql/conf.d/
+`), 0o600))
+
+	options, err := readOptionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"user":     "readonly",
+		"password": "p#ss word",
+		"host":     "db.internal",
+		"port":     "3307",
+		"ssl-ca":   "/etc/mysql/ca.pem",
+	}, options)
+
+	config, err := DBConfig{OptionFile: path}.withOptionFile()
+	require.NoError(t, err)
+	assert.Equal(t, DBConfig{OptionFile: path, User: "readonly", Password: "p#ss word", Host: "db.internal", Port: "3307", TLSCA: "/etc/mysql/ca.pem"}, config)
+
+	config, err = DBConfig{OptionFile: path, User: "app", Socket: "/tmp/mysql.sock", PasswordCommand: "echo token"}.withOptionFile()
+	require.NoError(t, err)
+	assert.Equal(t, "app", config.User, "settings take precedence over the option file")
+	assert.Empty(t, config.Host, "the option file address is not mixed with the socket")
+	assert.Empty(t, config.Password, "the password command takes precedence over the option file")
+
+	config, err = DBConfig{OptionFile: path, DSN: "u:p@tcp(a:3306)/"}.withOptionFile()
+	require.NoError(t, err)
+	assert.Equal(t, DBConfig{OptionFile: path, DSN: "u:p@tcp(a:3306)/", TLSCA: "/etc/mysql/ca.pem"}, config, "the DSN takes precedence over the option file")
+	hostOnly := filepath.Join(t.TempDir(), "host.cnf")
+	require.NoError(t, os.WriteFile(hostOnly, []byte("[client]\nhost=b\nuser=other\npassword=other\n"), 0o600))
+	cfg, err := DBConfig{OptionFile: hostOnly, DSN: "u:p@tcp(a:3306)/"}.mysqlConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "a:3306", cfg.Addr)
+	assert.Equal(t, "u", cfg.User)
+	assert.Equal(t, "p", cfg.Passwd)
+
+	config, err = DBConfig{OptionFile: path, DSN: "/app"}.withOptionFile()
+	require.NoError(t, err)
+	assert.Equal(t, "db.internal", config.Host, "the option file fills in what the DSN doesn't set")
+	assert.Equal(t, "p#ss word", config.Password)
+
+	_, err = DBConfig{OptionFile: path, DSN: "u:p@tcp(a:3306)"}.withOptionFile()
+	assert.ErrorContains(t, err, "invalid dsn")
+
+	_, err = DBConfig{OptionFile: filepath.Join(t.TempDir(), "missing.cnf")}.withOptionFile()
+	assert.ErrorContains(t, err, "failed to read the option file")
+}
@@ -4,35 +4,70 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 )
 
+// DBConfig is how to connect to a MySQL server. See mysqlConfig for how the settings are combined with DSN
 type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
+	DSN      string `yaml:"dsn,omitempty"` // A go-sql-driver/mysql DSN, which the other settings override
+	Host     string `yaml:"host,omitempty"`
+	Port     string `yaml:"port,omitempty"`
+	Socket   string `yaml:"socket,omitempty"` // Unix domain socket, which is used instead of Host and Port
+	User     string `yaml:"user,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	PasswordFile    string `yaml:"passwordFile,omitempty"`    // File of the password, read on each connection
+	PasswordCommand string `yaml:"passwordCommand,omitempty"` // Shell command whose output is the password, run on each connection
+	OptionFile      string `yaml:"optionFile,omitempty"`      // MySQL option file such as ~/.my.cnf, whose [client] section fills in the unset settings
+
+	TLS             string        `yaml:"tls,omitempty"`             // false, true, skip-verify or preferred
+	TLSCA           string        `yaml:"tlsCA,omitempty"`           // CA file to verify the server certificate with
+	TLSCert         string        `yaml:"tlsCert,omitempty"`         // Client certificate file
+	TLSKey          string        `yaml:"tlsKey,omitempty"`          // Private key file of TLSCert
+	ServerPublicKey string        `yaml:"serverPublicKey,omitempty"` // RSA public key file of the server for caching_sha2_password and sha256_password
+	ConnectTimeout  time.Duration `yaml:"connectTimeout,omitempty"`
+	ReadTimeout     time.Duration `yaml:"readTimeout,omitempty"`
+	Charset         string        `yaml:"charset,omitempty"`
+	TimeZone        string        `yaml:"timeZone,omitempty"` // Session time_zone, e.g. +00:00 or Asia/Tokyo
 }
 
 type TableSummary struct {
-	Name    string
-	Comment string
-	PK      []string     // Primary key columns
-	UK      []UniqueKey  // Unique key information
-	FK      []ForeignKey // Foreign key information
+	Name    string       `json:"name"`
+	Comment string       `json:"comment"`
+	PK      []string     `json:"primaryKey"`        // Primary key columns
+	UK      []UniqueKey  `json:"uniqueKeys"`        // Unique key information
+	FK      []ForeignKey `json:"foreignKeys"`       // Foreign key information
+	Columns []ColumnInfo `json:"columns,omitempty"` // Column information, only set by list_tables with detail "full"
 }
 
 type UniqueKey struct {
-	Name    string
-	Columns []string
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
 }
 
 type ForeignKey struct {
-	Name       string
-	Columns    []string
-	RefTable   string
-	RefColumns []string
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"refTable"`
+	RefColumns []string `json:"refColumns"`
+	Origin     FKOrigin `json:"origin,omitempty"`     // Where the relationship comes from
+	Confidence float64  `json:"confidence,omitempty"` // Confidence score of inferred relationships (0.0 - 1.0)
+	Redacted   bool     `json:"redacted,omitempty"`   // The referenced table is hidden, so RefTable and RefColumns are empty
 }
 
+// FKOrigin describes how a foreign key relationship was obtained
+type FKOrigin string
+
+const (
+	// FKOriginDeclared is a foreign key constraint declared in the database
+	FKOriginDeclared FKOrigin = ""
+	// FKOriginInferred is a relationship inferred from naming conventions
+	FKOriginInferred FKOrigin = "inferred"
+	// FKOriginVirtual is a relationship declared in the virtual foreign key config file
+	FKOriginVirtual FKOrigin = "virtual"
+)
+
 type ColumnInfo struct {
 	Name       string
 	Type       string
@@ -42,34 +77,94 @@ type ColumnInfo struct {
 }
 
 type IndexInfo struct {
-	Name    string
-	Columns []string
-	Unique  bool
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+// DB is the source of schema information. NewDB returns the implementation backed by a live MySQL connection,
+// and NewSnapshotDB the one backed by schema snapshot files for offline use
+type DB interface {
+	FetchAllTableSummaries(ctx context.Context, dbName string) ([]TableSummary, error)
+	FetchTableSummaries(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error)
+	FetchTableWithComments(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error)
+	CountTables(ctx context.Context, dbName string, filter TableFilter) (total int, after int, err error)
+	FetchPrimaryKeys(ctx context.Context, dbName string, tableName string) ([]string, error)
+	FetchUniqueKeys(ctx context.Context, dbName string, tableName string) ([]UniqueKey, error)
+	FetchForeignKeys(ctx context.Context, dbName string, tableName string) ([]ForeignKey, error)
+	FetchTableColumns(ctx context.Context, dbName string, tableName string) ([]ColumnInfo, error)
+	FetchTableIndexes(ctx context.Context, dbName string, tableName string) ([]IndexInfo, error)
+	FetchAllColumns(ctx context.Context, dbName string) (map[string][]ColumnInfo, error)
+	FetchColumns(ctx context.Context, dbName string, tableNames []string) (map[string][]ColumnInfo, error)
+	FetchIndexedColumns(ctx context.Context, dbName string) (map[string]map[string]bool, error)
+	FetchColumnIndexes(ctx context.Context, dbName string, tableName string, columnName string) ([]IndexInfo, error)
+	FetchReferencingForeignKeys(ctx context.Context, dbName string, tableName string) ([]DependencyEdge, error)
+	FetchGeneratedColumns(ctx context.Context, dbName string, tableName string) ([]GeneratedColumn, error)
+	FetchViews(ctx context.Context, dbName string) ([]SchemaObject, error)
+	FetchTriggers(ctx context.Context, dbName string) ([]SchemaObject, error)
+	FetchRoutines(ctx context.Context, dbName string) ([]SchemaObject, error)
+	FetchObjectTypes(ctx context.Context, dbName string, name string) ([]string, error)
+	FetchCreateStatement(ctx context.Context, dbName string, objectType string, name string) (string, error)
 }
 
-type DB struct {
+// mysqlDB reads schema information from INFORMATION_SCHEMA of a live MySQL server
+type mysqlDB struct {
 	conn *sql.DB
 }
 
-func NewDB(conn *sql.DB) *DB {
-	return &DB{conn: conn}
+func NewDB(conn *sql.DB) DB {
+	return &mysqlDB{conn: conn}
 }
 
-func connectDB(config DBConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/",
-		config.User, config.Password, config.Host, config.Port)
+// TableFilter narrows down the tables returned by FetchTableWithComments. The zero value matches all tables
+type TableFilter struct {
+	After      string // Only tables whose name comes after this name (used for cursors)
+	Limit      int    // Maximum number of tables (0 means unlimited)
+	Prefix     string // Only tables whose name starts with this prefix
+	Pattern    string // Only tables whose name matches this LIKE pattern
+	HasComment *bool  // Only tables with (true) or without (false) a comment
+}
 
-	conn, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, err
+// IsZero reports whether the filter matches all tables
+func (f TableFilter) IsZero() bool {
+	return f == TableFilter{}
+}
+
+// conditions builds the SQL conditions of the filter, excluding After and Limit
+func (f TableFilter) conditions() (string, []any) {
+	var conds strings.Builder
+	var args []any
+	if f.Prefix != "" {
+		conds.WriteString(" AND TABLE_NAME LIKE ?")
+		args = append(args, escapeLike(f.Prefix)+"%")
 	}
+	if f.Pattern != "" {
+		conds.WriteString(" AND TABLE_NAME LIKE ?")
+		args = append(args, f.Pattern)
+	}
+	if f.HasComment != nil {
+		if *f.HasComment {
+			conds.WriteString(" AND IFNULL(TABLE_COMMENT, '') != ''")
+		} else {
+			conds.WriteString(" AND IFNULL(TABLE_COMMENT, '') = ''")
+		}
+	}
+	return conds.String(), args
+}
 
-	return conn, nil
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
 // FetchAllTableSummaries gets summary information for all tables in the database
-func (db *DB) FetchAllTableSummaries(ctx context.Context, dbName string) ([]TableSummary, error) {
-	tables, err := db.FetchTableWithComments(ctx, dbName)
+func (db *mysqlDB) FetchAllTableSummaries(ctx context.Context, dbName string) ([]TableSummary, error) {
+	return db.FetchTableSummaries(ctx, dbName, TableFilter{})
+}
+
+// FetchTableSummaries gets summary information for the tables matching the filter
+func (db *mysqlDB) FetchTableSummaries(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	tables, err := db.FetchTableWithComments(ctx, dbName, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -95,8 +190,19 @@ func (db *DB) FetchAllTableSummaries(ctx context.Context, dbName string) ([]Tabl
 	return tables, nil
 }
 
-// FetchTableWithComments gets table names and comments
-func (db *DB) FetchTableWithComments(ctx context.Context, dbName string) ([]TableSummary, error) {
+// FetchTableWithComments gets table names and comments of the tables matching the filter
+func (db *mysqlDB) FetchTableWithComments(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	conds, condArgs := filter.conditions()
+	args := append([]any{dbName}, condArgs...)
+	if filter.After != "" {
+		conds += " AND TABLE_NAME > ?"
+		args = append(args, filter.After)
+	}
+	limit := ""
+	if filter.Limit > 0 {
+		limit = fmt.Sprintf("LIMIT %d", filter.Limit)
+	}
+
 	query := `
 		SELECT 
 			TABLE_NAME, 
@@ -104,12 +210,12 @@ func (db *DB) FetchTableWithComments(ctx context.Context, dbName string) ([]Tabl
 		FROM 
 			INFORMATION_SCHEMA.TABLES 
 		WHERE 
-			TABLE_SCHEMA = ? 
+			TABLE_SCHEMA = ?` + conds + `
 		ORDER BY 
 			TABLE_NAME
-	`
+		` + limit
 
-	rows, err := db.conn.QueryContext(ctx, query, dbName)
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -131,8 +237,30 @@ func (db *DB) FetchTableWithComments(ctx context.Context, dbName string) ([]Tabl
 	return tables, nil
 }
 
+// CountTables counts the tables matching the filter, ignoring After and Limit, and how many of them come after
+// After. Both are counted at once, so that list_tables gets the total and the remaining tables of a page in one query
+func (db *mysqlDB) CountTables(ctx context.Context, dbName string, filter TableFilter) (int, int, error) {
+	conds, condArgs := filter.conditions()
+	args := append([]any{filter.After, dbName}, condArgs...)
+
+	query := `
+		SELECT 
+			COUNT(*),
+			IFNULL(SUM(TABLE_NAME > ?), 0)
+		FROM 
+			INFORMATION_SCHEMA.TABLES 
+		WHERE 
+			TABLE_SCHEMA = ?` + conds
+
+	var total, after int
+	if err := db.conn.QueryRowContext(ctx, query, args...).Scan(&total, &after); err != nil {
+		return 0, 0, err
+	}
+	return total, after, nil
+}
+
 // FetchPrimaryKeys gets the primary key columns of a table
-func (db *DB) FetchPrimaryKeys(ctx context.Context, dbName string, tableName string) ([]string, error) {
+func (db *mysqlDB) FetchPrimaryKeys(ctx context.Context, dbName string, tableName string) ([]string, error) {
 	query := `
 		SELECT 
 			COLUMN_NAME
@@ -169,7 +297,7 @@ func (db *DB) FetchPrimaryKeys(ctx context.Context, dbName string, tableName str
 }
 
 // FetchUniqueKeys gets the unique key constraints of a table
-func (db *DB) FetchUniqueKeys(ctx context.Context, dbName string, tableName string) ([]UniqueKey, error) {
+func (db *mysqlDB) FetchUniqueKeys(ctx context.Context, dbName string, tableName string) ([]UniqueKey, error) {
 	query := `
 		SELECT 
 			kcu.CONSTRAINT_NAME,
@@ -228,7 +356,7 @@ func (db *DB) FetchUniqueKeys(ctx context.Context, dbName string, tableName stri
 }
 
 // FetchForeignKeys gets the foreign key constraints of a table
-func (db *DB) FetchForeignKeys(ctx context.Context, dbName string, tableName string) ([]ForeignKey, error) {
+func (db *mysqlDB) FetchForeignKeys(ctx context.Context, dbName string, tableName string) ([]ForeignKey, error) {
 	query := `
 		SELECT 
 			kcu.CONSTRAINT_NAME,
@@ -286,7 +414,7 @@ func (db *DB) FetchForeignKeys(ctx context.Context, dbName string, tableName str
 }
 
 // FetchTableColumns gets the column information of a table
-func (db *DB) FetchTableColumns(ctx context.Context, dbName string, tableName string) ([]ColumnInfo, error) {
+func (db *mysqlDB) FetchTableColumns(ctx context.Context, dbName string, tableName string) ([]ColumnInfo, error) {
 	query := `
 		SELECT 
 			COLUMN_NAME, 
@@ -326,7 +454,7 @@ func (db *DB) FetchTableColumns(ctx context.Context, dbName string, tableName st
 }
 
 // FetchTableIndexes gets the index information of a table
-func (db *DB) FetchTableIndexes(ctx context.Context, dbName string, tableName string) ([]IndexInfo, error) {
+func (db *mysqlDB) FetchTableIndexes(ctx context.Context, dbName string, tableName string) ([]IndexInfo, error) {
 	query := `
 		SELECT 
 			INDEX_NAME, 
@@ -381,3 +509,489 @@ func (db *DB) FetchTableIndexes(ctx context.Context, dbName string, tableName st
 	}
 	return indexes, nil
 }
+
+// FetchAllColumns gets the column information of all tables in the database, keyed by table name
+func (db *mysqlDB) FetchAllColumns(ctx context.Context, dbName string) (map[string][]ColumnInfo, error) {
+	return db.fetchColumns(ctx, dbName, "")
+}
+
+// FetchColumns gets the column information of the tables, keyed by table name
+func (db *mysqlDB) FetchColumns(ctx context.Context, dbName string, tableNames []string) (map[string][]ColumnInfo, error) {
+	if len(tableNames) == 0 {
+		return map[string][]ColumnInfo{}, nil
+	}
+	args := make([]any, len(tableNames))
+	for i, name := range tableNames {
+		args[i] = name
+	}
+	return db.fetchColumns(ctx, dbName, " AND TABLE_NAME IN (?"+strings.Repeat(", ?", len(tableNames)-1)+")", args...)
+}
+
+// fetchColumns gets the column information of the tables matching the conditions, keyed by table name
+func (db *mysqlDB) fetchColumns(ctx context.Context, dbName string, conds string, args ...any) (map[string][]ColumnInfo, error) {
+	query := `
+		SELECT 
+			TABLE_NAME,
+			COLUMN_NAME, 
+			COLUMN_TYPE, 
+			IS_NULLABLE, 
+			COLUMN_DEFAULT, 
+			IFNULL(COLUMN_COMMENT, '') AS COLUMN_COMMENT
+		FROM 
+			INFORMATION_SCHEMA.COLUMNS 
+		WHERE 
+			TABLE_SCHEMA = ?` + conds + `
+		ORDER BY 
+			TABLE_NAME,
+			ORDINAL_POSITION
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, append([]any{dbName}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]ColumnInfo)
+	for rows.Next() {
+		var tableName string
+		var col ColumnInfo
+		if err := rows.Scan(&tableName, &col.Name, &col.Type, &col.IsNullable, &col.Default, &col.Comment); err != nil {
+			return nil, err
+		}
+		columns[tableName] = append(columns[tableName], col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// FetchIndexedColumns gets the columns that are the leading column of any index (including PK, UK and FK indexes), keyed by table name
+func (db *mysqlDB) FetchIndexedColumns(ctx context.Context, dbName string) (map[string]map[string]bool, error) {
+	query := `
+		SELECT 
+			TABLE_NAME,
+			COLUMN_NAME
+		FROM 
+			INFORMATION_SCHEMA.STATISTICS 
+		WHERE 
+			TABLE_SCHEMA = ? 
+			AND SEQ_IN_INDEX = 1
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexed := make(map[string]map[string]bool)
+	for rows.Next() {
+		var tableName, columnName string
+		if err := rows.Scan(&tableName, &columnName); err != nil {
+			return nil, err
+		}
+		if indexed[tableName] == nil {
+			indexed[tableName] = make(map[string]bool)
+		}
+		indexed[tableName][columnName] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return indexed, nil
+}
+
+// GeneratedColumn is a generated column and its expression
+type GeneratedColumn struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// SchemaObject is a view, trigger or routine with its definition body
+type SchemaObject struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`             // VIEW, TRIGGER, PROCEDURE or FUNCTION
+	Table      string `json:"table,omitempty"`  // The table a trigger belongs to
+	Timing     string `json:"timing,omitempty"` // BEFORE or AFTER (triggers only)
+	Event      string `json:"event,omitempty"`  // INSERT, UPDATE or DELETE (triggers only)
+	Definition string `json:"definition"`
+}
+
+// FetchColumnIndexes gets all indexes of a table (including PRIMARY and unique keys) that contain the column
+func (db *mysqlDB) FetchColumnIndexes(ctx context.Context, dbName string, tableName string, columnName string) ([]IndexInfo, error) {
+	query := `
+		SELECT 
+			INDEX_NAME, 
+			COLUMN_NAME,
+			NON_UNIQUE 
+		FROM 
+			INFORMATION_SCHEMA.STATISTICS 
+		WHERE 
+			TABLE_SCHEMA = ? 
+			AND TABLE_NAME = ? 
+			AND INDEX_NAME IN (
+				SELECT INDEX_NAME 
+				FROM INFORMATION_SCHEMA.STATISTICS 
+				WHERE TABLE_SCHEMA = ? 
+				AND TABLE_NAME = ? 
+				AND COLUMN_NAME = ?
+			)
+		ORDER BY 
+			INDEX_NAME = 'PRIMARY' DESC,
+			INDEX_NAME, 
+			SEQ_IN_INDEX
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName, tableName, dbName, tableName, columnName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Build information while maintaining the order of SQL acquisition
+	var indexes []IndexInfo
+	var currentIdx *IndexInfo
+	for rows.Next() {
+		var indexName, colName string
+		var nonUnique bool
+		if err := rows.Scan(&indexName, &colName, &nonUnique); err != nil {
+			return nil, err
+		}
+		if currentIdx == nil || currentIdx.Name != indexName {
+			newIdx := IndexInfo{
+				Name:    indexName,
+				Unique:  !nonUnique,
+				Columns: []string{},
+			}
+			indexes = append(indexes, newIdx)
+			currentIdx = &indexes[len(indexes)-1]
+		}
+		currentIdx.Columns = append(currentIdx.Columns, colName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
+
+// FetchReferencingForeignKeys gets the foreign key constraints in any database that reference a table.
+// Tables in another database are qualified with the database name.
+func (db *mysqlDB) FetchReferencingForeignKeys(ctx context.Context, dbName string, tableName string) ([]DependencyEdge, error) {
+	query := `
+		SELECT 
+			kcu.TABLE_SCHEMA,
+			kcu.TABLE_NAME,
+			kcu.CONSTRAINT_NAME,
+			kcu.COLUMN_NAME,
+			kcu.REFERENCED_COLUMN_NAME
+		FROM 
+			INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+		WHERE 
+			kcu.REFERENCED_TABLE_SCHEMA = ? 
+			AND kcu.REFERENCED_TABLE_NAME = ? 
+		ORDER BY 
+			kcu.TABLE_SCHEMA,
+			kcu.TABLE_NAME,
+			kcu.CONSTRAINT_NAME,
+			kcu.ORDINAL_POSITION
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Build information while maintaining the order of SQL acquisition
+	var edges []DependencyEdge
+	var current *DependencyEdge
+	for rows.Next() {
+		var schemaName, table, constraintName, columnName, refColumnName string
+		if err := rows.Scan(&schemaName, &table, &constraintName, &columnName, &refColumnName); err != nil {
+			return nil, err
+		}
+		if schemaName != dbName {
+			table = schemaName + "." + table
+		}
+
+		if current == nil || current.Table != table || current.FK.Name != constraintName {
+			edges = append(edges, DependencyEdge{
+				Table: table,
+				FK: ForeignKey{
+					Name:     constraintName,
+					RefTable: tableName,
+				},
+			})
+			current = &edges[len(edges)-1]
+		}
+
+		current.FK.Columns = append(current.FK.Columns, columnName)
+		current.FK.RefColumns = append(current.FK.RefColumns, refColumnName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return edges, nil
+}
+
+// FetchGeneratedColumns gets the generated columns of a table and their expressions
+func (db *mysqlDB) FetchGeneratedColumns(ctx context.Context, dbName string, tableName string) ([]GeneratedColumn, error) {
+	query := `
+		SELECT 
+			COLUMN_NAME,
+			GENERATION_EXPRESSION
+		FROM 
+			INFORMATION_SCHEMA.COLUMNS 
+		WHERE 
+			TABLE_SCHEMA = ? 
+			AND TABLE_NAME = ? 
+			AND IFNULL(GENERATION_EXPRESSION, '') != ''
+		ORDER BY 
+			ORDINAL_POSITION
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []GeneratedColumn
+	for rows.Next() {
+		var col GeneratedColumn
+		if err := rows.Scan(&col.Name, &col.Expression); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// FetchViews gets the views of the database and their definitions
+func (db *mysqlDB) FetchViews(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	query := `
+		SELECT 
+			TABLE_NAME,
+			IFNULL(VIEW_DEFINITION, '')
+		FROM 
+			INFORMATION_SCHEMA.VIEWS 
+		WHERE 
+			TABLE_SCHEMA = ? 
+		ORDER BY 
+			TABLE_NAME
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []SchemaObject
+	for rows.Next() {
+		view := SchemaObject{Type: "VIEW"}
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// FetchTriggers gets the triggers of the database and their bodies
+func (db *mysqlDB) FetchTriggers(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	query := `
+		SELECT 
+			TRIGGER_NAME,
+			EVENT_OBJECT_TABLE,
+			ACTION_TIMING,
+			EVENT_MANIPULATION,
+			ACTION_STATEMENT
+		FROM 
+			INFORMATION_SCHEMA.TRIGGERS 
+		WHERE 
+			TRIGGER_SCHEMA = ? 
+		ORDER BY 
+			EVENT_OBJECT_TABLE,
+			TRIGGER_NAME
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []SchemaObject
+	for rows.Next() {
+		trigger := SchemaObject{Type: "TRIGGER"}
+		if err := rows.Scan(&trigger.Name, &trigger.Table, &trigger.Timing, &trigger.Event, &trigger.Definition); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return triggers, nil
+}
+
+// FetchRoutines gets the stored procedures and functions of the database and their bodies
+func (db *mysqlDB) FetchRoutines(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	query := `
+		SELECT 
+			ROUTINE_NAME,
+			ROUTINE_TYPE,
+			IFNULL(ROUTINE_DEFINITION, '')
+		FROM 
+			INFORMATION_SCHEMA.ROUTINES 
+		WHERE 
+			ROUTINE_SCHEMA = ? 
+		ORDER BY 
+			ROUTINE_TYPE,
+			ROUTINE_NAME
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routines []SchemaObject
+	for rows.Next() {
+		var routine SchemaObject
+		if err := rows.Scan(&routine.Name, &routine.Type, &routine.Definition); err != nil {
+			return nil, err
+		}
+		routines = append(routines, routine)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return routines, nil
+}
+
+// FetchObjectTypes gets the types of the schema objects with the given name: TABLE, VIEW, PROCEDURE, FUNCTION or TRIGGER.
+// More than one type is returned when e.g. a procedure and a function share the name
+func (db *mysqlDB) FetchObjectTypes(ctx context.Context, dbName string, name string) ([]string, error) {
+	query := `
+		SELECT IF(TABLE_TYPE = 'VIEW', 'VIEW', 'TABLE') 
+		FROM INFORMATION_SCHEMA.TABLES 
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		UNION ALL
+		SELECT ROUTINE_TYPE 
+		FROM INFORMATION_SCHEMA.ROUTINES 
+		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ?
+		UNION ALL
+		SELECT 'TRIGGER' 
+		FROM INFORMATION_SCHEMA.TRIGGERS 
+		WHERE TRIGGER_SCHEMA = ? AND TRIGGER_NAME = ?
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, dbName, name, dbName, name, dbName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var objectType string
+		if err := rows.Scan(&objectType); err != nil {
+			return nil, err
+		}
+		types = append(types, objectType)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// createStatementColumns maps object types to the column of SHOW CREATE that holds the statement
+var createStatementColumns = map[string]string{
+	"TABLE":     "Create Table",
+	"VIEW":      "Create View",
+	"PROCEDURE": "Create Procedure",
+	"FUNCTION":  "Create Function",
+	"TRIGGER":   "SQL Original Statement",
+}
+
+// FetchCreateStatement gets the output of SHOW CREATE <objectType> for the object
+func (db *mysqlDB) FetchCreateStatement(ctx context.Context, dbName string, objectType string, name string) (string, error) {
+	column, ok := createStatementColumns[objectType]
+	if !ok {
+		return "", fmt.Errorf("unsupported object type: %s", objectType)
+	}
+
+	// Identifiers can't be placeholders, so they are quoted instead
+	query := fmt.Sprintf("SHOW CREATE %s %s.%s", objectType, quoteIdentifier(dbName), quoteIdentifier(name))
+
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	// The columns of SHOW CREATE differ by object type, so the statement is picked by column name
+	names, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s %s not found", strings.ToLower(objectType), name)
+	}
+	values := make([]sql.NullString, len(names))
+	dest := make([]any, len(names))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+
+	for i, n := range names {
+		if n != column {
+			continue
+		}
+		if !values[i].Valid {
+			// MySQL returns NULL for routines the user is not allowed to see the body of
+			return "", fmt.Errorf("the definition of %s %s is not visible to the current user", strings.ToLower(objectType), name)
+		}
+		return values[i].String, nil
+	}
+
+	return "", fmt.Errorf("SHOW CREATE %s returned no %q column", objectType, column)
+}
+
+// quoteIdentifier quotes a MySQL identifier with backquotes
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"regexp"
+)
+
+// CreateStatement is the DDL of a schema object as returned by SHOW CREATE
+type CreateStatement struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"` // TABLE, VIEW, PROCEDURE, FUNCTION or TRIGGER
+	Statement string `json:"statement"`
+}
+
+// objectTypes lists the object types accepted by show_create
+var objectTypes = []string{"table", "view", "procedure", "function", "trigger"}
+
+var (
+	autoIncrementOption = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+	definerClause       = regexp.MustCompile("DEFINER=(`[^`]*`|'[^']*'|[^@\\s]+)@(`[^`]*`|'[^']*'|\\S+) ")
+)
+
+// StripDDLNoise removes the parts of a CREATE statement that depend on the server rather than the schema:
+// the AUTO_INCREMENT= table option and the DEFINER= clause of views, routines and triggers
+func StripDDLNoise(statement string) string {
+	statement = autoIncrementOption.ReplaceAllString(statement, "")
+	return definerClause.ReplaceAllString(statement, "")
+}
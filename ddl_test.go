@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripDDLNoise(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		expected  string
+	}{
+		{
+			name:      "AUTO_INCREMENT table option",
+			statement: "CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4",
+			expected:  "CREATE TABLE `users` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		{
+			name:      "DEFINER of a view",
+			statement: "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1 AS `1`",
+			expected:  "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v` AS select 1 AS `1`",
+		},
+		{
+			name:      "DEFINER of a routine with quoted host",
+			statement: "CREATE DEFINER='app'@'10.0.0.%' PROCEDURE `p`()\nBEGIN\nEND",
+			expected:  "CREATE PROCEDURE `p`()\nBEGIN\nEND",
+		},
+		{
+			name:      "no noise",
+			statement: "CREATE TABLE `t` (\n  `id` int NOT NULL\n) ENGINE=InnoDB",
+			expected:  "CREATE TABLE `t` (\n  `id` int NOT NULL\n) ENGINE=InnoDB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, StripDDLNoise(tt.statement))
+		})
+	}
+}
@@ -0,0 +1,1025 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ParseDDLFile parses a file of CREATE statements, such as the output of `mysqldump --no-data`, into snapshots.
+// Tables are put in the database selected by USE, or in a database named after the file when there is none
+func ParseDDLFile(path string) ([]SchemaSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dbName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	snapshots, err := ParseDDL(string(data), dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return snapshots, nil
+}
+
+// ParseDDL parses CREATE TABLE, CREATE INDEX, ALTER TABLE ... ADD, CREATE VIEW, CREATE TRIGGER and
+// CREATE FUNCTION/PROCEDURE statements into snapshots, one per database. Other statements are ignored.
+// Index and constraint names that MySQL generates are reproduced so that the result matches a live database
+func ParseDDL(ddl string, defaultDBName string) ([]SchemaSnapshot, error) {
+	statements, err := splitDDL(ddl)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ddlSchema{currentDB: defaultDBName, databases: map[string]*ddlDatabase{}}
+	for _, stmt := range statements {
+		if err := s.apply(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return s.snapshots(), nil
+}
+
+// ddlTokenKind is the kind of a token of a DDL statement
+type ddlTokenKind int
+
+const (
+	ddlWord       ddlTokenKind = iota // Keyword or unquoted identifier
+	ddlIdentifier                     // `quoted identifier`
+	ddlString                         // 'string literal'
+	ddlNumber
+	ddlSymbol
+)
+
+// ddlToken is a token of a DDL statement. start and end are offsets in the statement source
+type ddlToken struct {
+	kind       ddlTokenKind
+	text       string // Unquoted text for identifiers and strings
+	start, end int
+}
+
+// ddlStatement is a statement with its tokens and source text
+type ddlStatement struct {
+	tokens []ddlToken
+	source string
+}
+
+// splitDDL splits the DDL into statements and tokenizes them. It understands the DELIMITER command of the mysql
+// client and executes the content of /*!NNNNN ... */ comments like MySQL does, since mysqldump writes views there
+func splitDDL(ddl string) ([]ddlStatement, error) {
+	var statements []ddlStatement
+	var tokens []ddlToken
+	delimiter := ";"
+	conditional := 0
+
+	flush := func() {
+		if len(tokens) > 0 {
+			statements = append(statements, ddlStatement{tokens: tokens, source: ddl})
+		}
+		tokens = nil
+	}
+
+	for i := 0; i < len(ddl); {
+		c := ddl[i]
+		rest := ddl[i:]
+		switch {
+		case strings.HasPrefix(rest, delimiter):
+			flush()
+			i += len(delimiter)
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case len(tokens) == 0 && len(rest) > len("DELIMITER ") && strings.EqualFold(rest[:len("DELIMITER ")], "DELIMITER "):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			delimiter = strings.TrimSpace(rest[len("DELIMITER "):end])
+			if delimiter == "" {
+				return nil, fmt.Errorf("DELIMITER without a delimiter")
+			}
+			i += end
+		case strings.HasPrefix(rest, "-- ") || strings.HasPrefix(rest, "--\n") || rest == "--" || c == '#':
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*!"):
+			i += len("/*!")
+			for i < len(ddl) && ddl[i] >= '0' && ddl[i] <= '9' {
+				i++
+			}
+			conditional++
+		case strings.HasPrefix(rest, "*/") && conditional > 0:
+			i += len("*/")
+			conditional--
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += 2 + end + 2
+		case c == '\'' || c == '"' || c == '`':
+			text, n, err := scanQuoted(rest)
+			if err != nil {
+				return nil, err
+			}
+			kind := ddlString
+			if c == '`' {
+				kind = ddlIdentifier
+			}
+			tokens = append(tokens, ddlToken{kind: kind, text: text, start: i, end: i + n})
+			i += n
+		case c >= '0' && c <= '9':
+			n := 1
+			for n < len(rest) && (isWordByte(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			tokens = append(tokens, ddlToken{kind: ddlNumber, text: rest[:n], start: i, end: i + n})
+			i += n
+		case isWordByte(c):
+			n := 1
+			for n < len(rest) && isWordByte(rest[n]) {
+				n++
+			}
+			tokens = append(tokens, ddlToken{kind: ddlWord, text: rest[:n], start: i, end: i + n})
+			i += n
+		default:
+			tokens = append(tokens, ddlToken{kind: ddlSymbol, text: rest[:1], start: i, end: i + 1})
+			i++
+		}
+	}
+	flush()
+
+	return statements, nil
+}
+
+// scanQuoted reads a quoted string or identifier at the start of s and returns its unquoted text and length.
+// Quotes are escaped by doubling them, and backslash escapes are also accepted in strings
+func scanQuoted(s string) (string, int, error) {
+	quote := s[0]
+	var text strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				text.WriteByte('\n')
+			case 't':
+				text.WriteByte('\t')
+			case 'r':
+				text.WriteByte('\r')
+			case '0':
+				text.WriteByte(0)
+			default:
+				text.WriteByte(s[i])
+			}
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			text.WriteByte(quote)
+			i++
+		case s[i] == quote:
+			return text.String(), i + 1, nil
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated %c", quote)
+}
+
+// isWordByte reports whether the byte can be part of an unquoted identifier
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// ddlParser reads the tokens of a statement
+type ddlParser struct {
+	tokens []ddlToken
+	pos    int
+	source string
+}
+
+func (p *ddlParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *ddlParser) peek() ddlToken {
+	if p.done() {
+		return ddlToken{kind: ddlSymbol}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *ddlParser) next() ddlToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// isWord reports whether the next token is one of the keywords
+func (p *ddlParser) isWord(keywords ...string) bool {
+	t := p.peek()
+	if t.kind != ddlWord {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(t.text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptWords consumes the keywords if they come next, in order
+func (p *ddlParser) acceptWords(keywords ...string) bool {
+	for i, kw := range keywords {
+		if p.pos+i >= len(p.tokens) {
+			return false
+		}
+		t := p.tokens[p.pos+i]
+		if t.kind != ddlWord || !strings.EqualFold(t.text, kw) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+func (p *ddlParser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == ddlSymbol && t.text == symbol
+}
+
+func (p *ddlParser) acceptSymbol(symbol string) bool {
+	if p.isSymbol(symbol) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// identifier reads an identifier, quoted or not
+func (p *ddlParser) identifier() (string, error) {
+	t := p.peek()
+	if p.done() || (t.kind != ddlWord && t.kind != ddlIdentifier && t.kind != ddlString) {
+		return "", fmt.Errorf("expected an identifier near %q", p.near())
+	}
+	p.pos++
+	return t.text, nil
+}
+
+// qualifiedName reads [db.]name
+func (p *ddlParser) qualifiedName() (db string, name string, err error) {
+	name, err = p.identifier()
+	if err != nil {
+		return "", "", err
+	}
+	if p.acceptSymbol(".") {
+		db = name
+		if name, err = p.identifier(); err != nil {
+			return "", "", err
+		}
+	}
+	return db, name, nil
+}
+
+// parenthesized returns the tokens inside the parentheses that start at the current token and skips them
+func (p *ddlParser) parenthesized() ([]ddlToken, error) {
+	if !p.isSymbol("(") {
+		return nil, fmt.Errorf("expected ( near %q", p.near())
+	}
+	start := p.pos
+	depth := 0
+	for ; !p.done(); p.pos++ {
+		switch t := p.tokens[p.pos]; {
+		case t.kind == ddlSymbol && t.text == "(":
+			depth++
+		case t.kind == ddlSymbol && t.text == ")":
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.tokens[start+1 : p.pos-1], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unbalanced parentheses near %q", p.sourceOf(p.tokens[start:]))
+}
+
+// rest returns the source text from the current token to the end of the statement
+func (p *ddlParser) rest() string {
+	if p.done() {
+		return ""
+	}
+	return p.sourceOf(p.tokens[p.pos:])
+}
+
+// sourceOf returns the source text spanned by the tokens
+func (p *ddlParser) sourceOf(tokens []ddlToken) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return p.source[tokens[0].start:tokens[len(tokens)-1].end]
+}
+
+// near returns a short excerpt of the source at the current token for error messages
+func (p *ddlParser) near() string {
+	text := p.rest()
+	if len(text) > 40 {
+		text = text[:40]
+	}
+	return text
+}
+
+// splitTopLevel splits tokens at the commas that are not inside parentheses
+func splitTopLevel(tokens []ddlToken) [][]ddlToken {
+	var parts [][]ddlToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		if t.kind != ddlSymbol {
+			continue
+		}
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// ddlSchema accumulates the objects of the parsed statements
+type ddlSchema struct {
+	currentDB string
+	databases map[string]*ddlDatabase
+	order     []string // Database names in order of appearance
+}
+
+// ddlDatabase holds the objects of a database while parsing
+type ddlDatabase struct {
+	tables   []*ddlTable
+	views    []SchemaObject
+	triggers []SchemaObject
+	routines []SchemaObject
+}
+
+// ddlTable holds a table while parsing. Keys are finalized when the snapshot is built
+type ddlTable struct {
+	detail           TableDetail
+	generatedColumns []GeneratedColumn
+	fkIndexNames     []string // Name of the index to create for each foreign key when none can be used
+	unnamedFKs       int
+}
+
+func (s *ddlSchema) database(name string) *ddlDatabase {
+	if name == "" {
+		name = s.currentDB
+	}
+	db, ok := s.databases[name]
+	if !ok {
+		db = &ddlDatabase{}
+		s.databases[name] = db
+		s.order = append(s.order, name)
+	}
+	return db
+}
+
+func (d *ddlDatabase) table(name string) *ddlTable {
+	for _, t := range d.tables {
+		if t.detail.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// apply applies a statement to the schema
+func (s *ddlSchema) apply(stmt ddlStatement) error {
+	p := &ddlParser{tokens: stmt.tokens, source: stmt.source}
+
+	switch {
+	case p.acceptWords("USE"):
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		s.currentDB = name
+		return nil
+	case p.acceptWords("CREATE"):
+		return s.applyCreate(p)
+	case p.acceptWords("ALTER", "TABLE"):
+		return s.applyAlterTable(p)
+	}
+	return nil
+}
+
+func (s *ddlSchema) applyCreate(p *ddlParser) error {
+	p.acceptWords("OR", "REPLACE")
+	p.acceptWords("TEMPORARY")
+
+	// View and routine options that come before the object type
+	for {
+		switch {
+		case p.acceptWords("ALGORITHM"), p.acceptWords("SQL", "SECURITY"):
+			p.acceptSymbol("=")
+			p.next()
+			continue
+		case p.acceptWords("DEFINER"):
+			p.acceptSymbol("=")
+			p.next()
+			if p.acceptSymbol("@") {
+				p.next()
+			}
+			continue
+		}
+		break
+	}
+
+	switch {
+	case p.acceptWords("DATABASE"), p.acceptWords("SCHEMA"):
+		p.acceptWords("IF", "NOT", "EXISTS")
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		s.database(name)
+		return nil
+	case p.acceptWords("TABLE"):
+		return s.applyCreateTable(p)
+	case p.isWord("UNIQUE", "FULLTEXT", "SPATIAL", "INDEX"):
+		return s.applyCreateIndex(p)
+	case p.acceptWords("VIEW"):
+		db, name, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		if p.isSymbol("(") {
+			if _, err := p.parenthesized(); err != nil {
+				return err
+			}
+		}
+		if !p.acceptWords("AS") {
+			return fmt.Errorf("view %s: expected AS near %q", name, p.near())
+		}
+		d := s.database(db)
+		d.views = append(d.views, SchemaObject{Name: name, Type: "VIEW", Definition: p.rest()})
+		return nil
+	case p.acceptWords("TRIGGER"):
+		db, name, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		trigger := SchemaObject{Name: name, Type: "TRIGGER"}
+		trigger.Timing = strings.ToUpper(p.next().text)
+		trigger.Event = strings.ToUpper(p.next().text)
+		if !p.acceptWords("ON") {
+			return fmt.Errorf("trigger %s: expected ON near %q", name, p.near())
+		}
+		if _, trigger.Table, err = p.qualifiedName(); err != nil {
+			return err
+		}
+		p.acceptWords("FOR", "EACH", "ROW")
+		if p.isWord("FOLLOWS", "PRECEDES") {
+			p.pos += 2
+		}
+		trigger.Definition = p.rest()
+		d := s.database(db)
+		d.triggers = append(d.triggers, trigger)
+		return nil
+	case p.isWord("FUNCTION", "PROCEDURE"):
+		routineType := strings.ToUpper(p.next().text)
+		db, name, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		definition, err := p.routineBody()
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", strings.ToLower(routineType), name, err)
+		}
+		d := s.database(db)
+		d.routines = append(d.routines, SchemaObject{Name: name, Type: routineType, Definition: definition})
+		return nil
+	}
+	return nil
+}
+
+// routineBody skips the parameters, the return type and the characteristics of a routine, and returns its body
+// like INFORMATION_SCHEMA.ROUTINES.ROUTINE_DEFINITION
+func (p *ddlParser) routineBody() (string, error) {
+	if _, err := p.parenthesized(); err != nil {
+		return "", err
+	}
+	if p.acceptWords("RETURNS") {
+		p.next()
+		if p.isSymbol("(") {
+			if _, err := p.parenthesized(); err != nil {
+				return "", err
+			}
+		}
+	returnType:
+		for {
+			switch {
+			case p.acceptWords("UNSIGNED"), p.acceptWords("SIGNED"), p.acceptWords("ZEROFILL"):
+			case p.acceptWords("CHARACTER", "SET"), p.acceptWords("CHARSET"), p.acceptWords("COLLATE"):
+				p.next()
+			default:
+				break returnType
+			}
+		}
+	}
+
+	for {
+		switch {
+		case p.acceptWords("COMMENT"), p.acceptWords("LANGUAGE"), p.acceptWords("SQL", "SECURITY"):
+			p.next()
+		case p.acceptWords("NOT", "DETERMINISTIC"), p.acceptWords("DETERMINISTIC"),
+			p.acceptWords("CONTAINS", "SQL"), p.acceptWords("NO", "SQL"),
+			p.acceptWords("READS", "SQL", "DATA"), p.acceptWords("MODIFIES", "SQL", "DATA"):
+		default:
+			return p.rest(), nil
+		}
+	}
+}
+
+func (s *ddlSchema) applyCreateTable(p *ddlParser) error {
+	p.acceptWords("IF", "NOT", "EXISTS")
+	db, name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if !p.isSymbol("(") {
+		// CREATE TABLE ... LIKE and CREATE TABLE ... AS SELECT are not supported
+		return nil
+	}
+
+	d := s.database(db)
+	t := &ddlTable{detail: TableDetail{Name: name}}
+	if existing := d.table(name); existing != nil {
+		*existing = *t
+		t = existing
+	} else {
+		d.tables = append(d.tables, t)
+	}
+
+	definitions, err := p.parenthesized()
+	if err != nil {
+		return err
+	}
+	for _, def := range splitTopLevel(definitions) {
+		if err := t.addDefinition(&ddlParser{tokens: def, source: p.source}); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+
+	// Table options
+	for !p.done() {
+		if p.acceptWords("COMMENT") {
+			p.acceptSymbol("=")
+			t.detail.Comment = p.next().text
+			continue
+		}
+		p.next()
+	}
+
+	return nil
+}
+
+func (s *ddlSchema) applyCreateIndex(p *ddlParser) error {
+	kind := ""
+	if p.isWord("UNIQUE", "FULLTEXT", "SPATIAL") {
+		kind = strings.ToUpper(p.next().text)
+	}
+	if !p.acceptWords("INDEX") {
+		return nil
+	}
+	indexName, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if p.acceptWords("USING") {
+		p.next()
+	}
+	if !p.acceptWords("ON") {
+		return fmt.Errorf("index %s: expected ON near %q", indexName, p.near())
+	}
+	db, tableName, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+
+	t := s.database(db).table(tableName)
+	if t == nil {
+		return fmt.Errorf("index %s: table %s is not defined", indexName, tableName)
+	}
+	columns, err := p.keyColumns()
+	if err != nil {
+		return fmt.Errorf("index %s: %w", indexName, err)
+	}
+	if kind == "UNIQUE" {
+		t.detail.UniqueKeys = append(t.detail.UniqueKeys, UniqueKey{Name: indexName, Columns: columns})
+	} else {
+		t.detail.Indexes = append(t.detail.Indexes, IndexInfo{Name: indexName, Columns: columns})
+	}
+	return nil
+}
+
+// applyAlterTable applies the ADD clauses of ALTER TABLE. Other clauses are ignored
+func (s *ddlSchema) applyAlterTable(p *ddlParser) error {
+	db, name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	t := s.database(db).table(name)
+	if t == nil {
+		return fmt.Errorf("ALTER TABLE %s: table is not defined", name)
+	}
+
+	for _, clause := range splitTopLevel(p.tokens[p.pos:]) {
+		cp := &ddlParser{tokens: clause, source: p.source}
+		if !cp.acceptWords("ADD") {
+			continue
+		}
+		cp.acceptWords("COLUMN")
+		if cp.isSymbol("(") {
+			definitions, err := cp.parenthesized()
+			if err != nil {
+				return err
+			}
+			for _, def := range splitTopLevel(definitions) {
+				if err := t.addDefinition(&ddlParser{tokens: def, source: p.source}); err != nil {
+					return fmt.Errorf("ALTER TABLE %s: %w", name, err)
+				}
+			}
+			continue
+		}
+		if err := t.addDefinition(cp); err != nil {
+			return fmt.Errorf("ALTER TABLE %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// keyColumns reads the (col1, col2(10) DESC, ...) list of a key. Functional key parts are skipped
+func (p *ddlParser) keyColumns() ([]string, error) {
+	tokens, err := p.parenthesized()
+	if err != nil {
+		return nil, err
+	}
+	var columns []string
+	for _, part := range splitTopLevel(tokens) {
+		if len(part) == 0 || part[0].kind == ddlSymbol {
+			continue
+		}
+		columns = append(columns, part[0].text)
+	}
+	return columns, nil
+}
+
+// optionalIndexName reads the name of an index when one is given before the column list
+func (p *ddlParser) optionalIndexName() string {
+	name := ""
+	if !p.isSymbol("(") && !p.isWord("USING") {
+		name = p.next().text
+	}
+	if p.acceptWords("USING") {
+		p.next()
+	}
+	return name
+}
+
+// addDefinition adds a column, key or constraint definition of CREATE TABLE or ALTER TABLE ADD
+func (t *ddlTable) addDefinition(p *ddlParser) error {
+	if p.done() {
+		return nil
+	}
+
+	constraintName := ""
+	if p.acceptWords("CONSTRAINT") && !p.isWord("PRIMARY", "UNIQUE", "FOREIGN", "CHECK") {
+		constraintName = p.next().text
+	}
+
+	switch {
+	case p.acceptWords("PRIMARY", "KEY"):
+		p.optionalIndexName()
+		columns, err := p.keyColumns()
+		if err != nil {
+			return err
+		}
+		t.detail.PrimaryKeys = columns
+	case p.acceptWords("UNIQUE"):
+		if !p.acceptWords("KEY") {
+			p.acceptWords("INDEX")
+		}
+		name := p.optionalIndexName()
+		columns, err := p.keyColumns()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = constraintName
+		}
+		t.detail.UniqueKeys = append(t.detail.UniqueKeys, UniqueKey{Name: name, Columns: columns})
+	case p.acceptWords("FOREIGN", "KEY"):
+		indexName := p.optionalIndexName()
+		columns, err := p.keyColumns()
+		if err != nil {
+			return err
+		}
+		if !p.acceptWords("REFERENCES") {
+			return fmt.Errorf("expected REFERENCES near %q", p.near())
+		}
+		_, refTable, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		refColumns, err := p.keyColumns()
+		if err != nil {
+			return err
+		}
+		if constraintName == "" {
+			t.unnamedFKs++
+			constraintName = fmt.Sprintf("%s_ibfk_%d", t.detail.Name, t.unnamedFKs)
+		} else if indexName == "" {
+			indexName = constraintName
+		}
+		t.detail.ForeignKeys = append(t.detail.ForeignKeys, ForeignKey{
+			Name: constraintName, Columns: columns, RefTable: refTable, RefColumns: refColumns,
+		})
+		t.fkIndexNames = append(t.fkIndexNames, indexName)
+	case p.isWord("KEY", "INDEX", "FULLTEXT", "SPATIAL"):
+		if p.acceptWords("FULLTEXT") || p.acceptWords("SPATIAL") {
+			if !p.acceptWords("KEY") {
+				p.acceptWords("INDEX")
+			}
+		} else {
+			p.next()
+		}
+		name := p.optionalIndexName()
+		columns, err := p.keyColumns()
+		if err != nil {
+			return err
+		}
+		t.detail.Indexes = append(t.detail.Indexes, IndexInfo{Name: name, Columns: columns})
+	case p.isWord("CHECK"):
+		// Check constraints are not part of the schema information
+	default:
+		return t.addColumn(p)
+	}
+	return nil
+}
+
+// integerTypes are the integer types whose display width MySQL 8 no longer shows
+var integerTypes = map[string]bool{"tinyint": true, "smallint": true, "mediumint": true, "int": true, "bigint": true}
+
+// typeAliases maps type names to the name MySQL shows in INFORMATION_SCHEMA
+var typeAliases = map[string]string{"integer": "int", "bool": "tinyint(1)", "boolean": "tinyint(1)", "dec": "decimal", "numeric": "decimal", "fixed": "decimal"}
+
+// addColumn adds a column definition: name type [attributes...]
+func (t *ddlTable) addColumn(p *ddlParser) error {
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	column := ColumnInfo{Name: name, IsNullable: "YES"}
+
+	// Data type
+	typeName := strings.ToLower(p.next().text)
+	if p.acceptWords("PRECISION") {
+		typeName += " precision"
+	}
+	if alias, ok := typeAliases[typeName]; ok {
+		typeName = alias
+	}
+	args := ""
+	if p.isSymbol("(") {
+		tokens, err := p.parenthesized()
+		if err != nil {
+			return err
+		}
+		args = formatTypeArgs(tokens)
+	}
+	var modifiers []string
+	for p.isWord("UNSIGNED", "SIGNED", "ZEROFILL") {
+		modifier := strings.ToLower(p.next().text)
+		if modifier != "signed" {
+			modifiers = append(modifiers, modifier)
+		}
+	}
+	zerofill := len(modifiers) > 0 && modifiers[len(modifiers)-1] == "zerofill"
+	if zerofill && !strings.Contains(strings.Join(modifiers, " "), "unsigned") {
+		// ZEROFILL implies UNSIGNED
+		modifiers = append([]string{"unsigned"}, modifiers...)
+	}
+	switch {
+	case integerTypes[typeName] && !zerofill && !(typeName == "tinyint" && args == "1"):
+		args = ""
+	case args == "" && (typeName == "decimal"):
+		args = "10,0"
+	case args == "" && (typeName == "char" || typeName == "binary" || typeName == "bit"):
+		args = "1"
+	}
+	column.Type = typeName
+	if args != "" {
+		column.Type += "(" + args + ")"
+	}
+	if len(modifiers) > 0 {
+		column.Type += " " + strings.Join(modifiers, " ")
+	}
+
+	// Attributes
+	for !p.done() {
+		switch {
+		case p.acceptWords("NOT", "NULL"):
+			column.IsNullable = "NO"
+		case p.acceptWords("NULL"):
+		case p.acceptWords("DEFAULT"):
+			column.Default.String, column.Default.Valid = p.defaultValue()
+		case p.acceptWords("COMMENT"):
+			column.Comment = p.next().text
+		case p.acceptWords("PRIMARY", "KEY"), p.acceptWords("KEY"):
+			t.detail.PrimaryKeys = []string{name}
+		case p.acceptWords("UNIQUE"):
+			p.acceptWords("KEY")
+			t.detail.UniqueKeys = append(t.detail.UniqueKeys, UniqueKey{Columns: []string{name}})
+		case p.acceptWords("GENERATED", "ALWAYS", "AS"), p.acceptWords("AS"):
+			tokens, err := p.parenthesized()
+			if err != nil {
+				return err
+			}
+			t.generatedColumns = append(t.generatedColumns, GeneratedColumn{Name: name, Expression: p.sourceOf(tokens)})
+		case p.acceptWords("CHARACTER", "SET"), p.acceptWords("CHARSET"), p.acceptWords("COLLATE"),
+			p.acceptWords("ON", "UPDATE"), p.acceptWords("COLUMN_FORMAT"), p.acceptWords("STORAGE"), p.acceptWords("SRID"):
+			p.next()
+			if p.isSymbol("(") {
+				if _, err := p.parenthesized(); err != nil {
+					return err
+				}
+			}
+		case p.acceptWords("CHECK"), p.acceptWords("REFERENCES"):
+			// Inline REFERENCES is ignored by MySQL, so the rest of the definition is skipped
+			p.pos = len(p.tokens)
+		default:
+			p.next()
+		}
+	}
+
+	t.detail.Columns = append(t.detail.Columns, column)
+	return nil
+}
+
+// defaultValue reads the value after DEFAULT. NULL means no default value
+func (p *ddlParser) defaultValue() (string, bool) {
+	switch t := p.peek(); {
+	case p.isWord("NULL"):
+		p.next()
+		return "", false
+	case p.isSymbol("("):
+		tokens, _ := p.parenthesized()
+		return p.sourceOf(tokens), true
+	case p.isSymbol("-") || p.isSymbol("+"):
+		p.next()
+		return t.text + p.next().text, true
+	case t.kind == ddlWord:
+		p.next()
+		value := strings.ToUpper(t.text)
+		if strings.HasPrefix(value, "CURRENT_TIMESTAMP") || value == "NOW" || value == "LOCALTIMESTAMP" {
+			if p.isSymbol("(") {
+				tokens, _ := p.parenthesized()
+				if len(tokens) > 0 {
+					return "CURRENT_TIMESTAMP(" + p.sourceOf(tokens) + ")", true
+				}
+			}
+			return "CURRENT_TIMESTAMP", true
+		}
+		if t.kind == ddlWord && p.peek().kind == ddlString && strings.HasPrefix(t.text, "_") {
+			// Charset introducer such as _utf8mb4'text'
+			return p.next().text, true
+		}
+		return t.text, true
+	default:
+		p.next()
+		return t.text, true
+	}
+}
+
+// formatTypeArgs formats the arguments of a data type like INFORMATION_SCHEMA, e.g. enum('a','b') or decimal(10,2)
+func formatTypeArgs(tokens []ddlToken) string {
+	var args strings.Builder
+	for _, t := range tokens {
+		switch t.kind {
+		case ddlString:
+			args.WriteString("'" + strings.ReplaceAll(t.text, "'", "''") + "'")
+		default:
+			args.WriteString(t.text)
+		}
+	}
+	return args.String()
+}
+
+// finish names the unnamed keys and creates the indexes for foreign keys like MySQL does
+func (t *ddlTable) finish() TableDetail {
+	detail := t.detail
+
+	// Columns of the primary key are always NOT NULL
+	for i := range detail.Columns {
+		if containsFold(detail.PrimaryKeys, detail.Columns[i].Name) {
+			detail.Columns[i].IsNullable = "NO"
+		}
+	}
+
+	usedNames := map[string]bool{"PRIMARY": true}
+	for _, uk := range detail.UniqueKeys {
+		usedNames[strings.ToLower(uk.Name)] = true
+	}
+	for _, idx := range detail.Indexes {
+		usedNames[strings.ToLower(idx.Name)] = true
+	}
+	// Unnamed indexes are named after their first column, with a suffix when the name is taken
+	indexName := func(name string, columns []string) string {
+		if name != "" {
+			return name
+		}
+		name = columns[0]
+		for n := 2; usedNames[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", columns[0], n)
+		}
+		usedNames[strings.ToLower(name)] = true
+		return name
+	}
+	for i := range detail.UniqueKeys {
+		detail.UniqueKeys[i].Name = indexName(detail.UniqueKeys[i].Name, detail.UniqueKeys[i].Columns)
+	}
+	for i := range detail.Indexes {
+		detail.Indexes[i].Name = indexName(detail.Indexes[i].Name, detail.Indexes[i].Columns)
+	}
+
+	// A foreign key needs an index starting with its columns, which MySQL creates when there is none
+	hasIndexFor := func(columns []string) bool {
+		keys := [][]string{detail.PrimaryKeys}
+		for _, uk := range detail.UniqueKeys {
+			keys = append(keys, uk.Columns)
+		}
+		for _, idx := range detail.Indexes {
+			keys = append(keys, idx.Columns)
+		}
+		for _, key := range keys {
+			if len(key) >= len(columns) && slices.EqualFunc(key[:len(columns)], columns, strings.EqualFold) {
+				return true
+			}
+		}
+		return false
+	}
+	for i, fk := range detail.ForeignKeys {
+		if !hasIndexFor(fk.Columns) {
+			detail.Indexes = append(detail.Indexes, IndexInfo{Name: indexName(t.fkIndexNames[i], fk.Columns), Columns: fk.Columns})
+		}
+	}
+
+	// Like the live database, indexes named after a foreign key are not listed separately
+	var indexes []IndexInfo
+	for _, idx := range detail.Indexes {
+		if !slices.ContainsFunc(detail.ForeignKeys, func(fk ForeignKey) bool { return fk.Name == idx.Name }) {
+			indexes = append(indexes, idx)
+		}
+	}
+	detail.Indexes = indexes
+
+	sort.Slice(detail.UniqueKeys, func(a, b int) bool { return detail.UniqueKeys[a].Name < detail.UniqueKeys[b].Name })
+	sort.Slice(detail.ForeignKeys, func(a, b int) bool { return detail.ForeignKeys[a].Name < detail.ForeignKeys[b].Name })
+	sort.Slice(detail.Indexes, func(a, b int) bool { return detail.Indexes[a].Name < detail.Indexes[b].Name })
+
+	return detail
+}
+
+// snapshots builds a snapshot for each database
+func (s *ddlSchema) snapshots() []SchemaSnapshot {
+	var snapshots []SchemaSnapshot
+	for _, name := range s.order {
+		d := s.databases[name]
+		snapshot := SchemaSnapshot{
+			Database:         name,
+			Tables:           []TableDetail{},
+			GeneratedColumns: map[string][]GeneratedColumn{},
+			Views:            emptyIfNil(d.views),
+			Triggers:         emptyIfNil(d.triggers),
+			Routines:         emptyIfNil(d.routines),
+		}
+		for _, t := range d.tables {
+			snapshot.Tables = append(snapshot.Tables, t.finish())
+			if len(t.generatedColumns) > 0 {
+				snapshot.GeneratedColumns[t.detail.Name] = t.generatedColumns
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDDLFile(t *testing.T) {
+	// testdata/schema.sql has no USE statement, so the tables are put in a database named after the file
+	snapshots, err := ParseDDLFile("testdata/schema.sql")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "schema", snapshots[0].Database)
+
+	db, err := NewSnapshotDB(snapshots)
+	require.NoError(t, err)
+	handler := NewHandler(db, "schema")
+
+	t.Run("list_tables matches the live database", func(t *testing.T) {
+		result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		assert.Equal(t, `Tables in database "schema" (Total: 4)
+Format: Table Name - Table Comment [PK: Primary Key] [UK: Unique Key 1; Unique Key 2...] [FK: Foreign Key -> Referenced Table.Column; ...]
+* Composite keys (keys composed of multiple columns) are grouped in parentheses: (col1, col2)
+* Multiple different key constraints are separated by semicolons: key1; key2
+
+- order_items - Order details [PK: (order_id, item_seq)] [UK: (order_id, product_maker, product_internal_code)] [FK: order_id -> orders.id; (product_maker, product_internal_code) -> products.(maker_code, internal_code)]
+- orders - Order header [PK: id] [FK: user_id -> users.id]
+- products - Product master [PK: product_code] [UK: (maker_code, internal_code)]
+- users - User information [PK: id] [UK: email; (tenant_id, employee_id); username]
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("describe_tables matches the live database", func(t *testing.T) {
+		result, err := handler.DescribeTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"tableNames": []interface{}{"products", "order_items"},
+			}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		assert.Equal(t, `# Table: products - Product master
+
+## Columns
+- product_code: varchar(50) NOT NULL [Product code (Primary Key)]
+- maker_code: varchar(50) NOT NULL [Maker code]
+- internal_code: varchar(50) NOT NULL [Internal product code]
+- product_name: varchar(255) NULL [Product name]
+
+## Key Information
+[PK: product_code]
+[UK: (maker_code, internal_code)]
+[INDEX: (maker_code, product_name); product_name]
+
+---
+
+# Table: order_items - Order details
+
+## Columns
+- order_id: int NOT NULL [Order ID (FK)]
+- item_seq: int NOT NULL [Order item sequence number]
+- product_maker: varchar(50) NOT NULL [Product maker code (FK)]
+- product_internal_code: varchar(50) NOT NULL [Product internal code (FK)]
+- quantity: int NOT NULL [Quantity]
+
+## Key Information
+[PK: (order_id, item_seq)]
+[UK: (order_id, product_maker, product_internal_code)]
+[FK: order_id -> orders.id; (product_maker, product_internal_code) -> products.(maker_code, internal_code)]
+[INDEX: (product_maker, product_internal_code)]
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("generated names follow MySQL", func(t *testing.T) {
+		fks, err := db.FetchForeignKeys(t.Context(), "schema", "order_items")
+		require.NoError(t, err)
+		require.Len(t, fks, 2)
+		assert.Equal(t, "order_items_ibfk_1", fks[0].Name)
+		assert.Equal(t, "order_items_ibfk_2", fks[1].Name)
+
+		indexes, err := db.FetchTableIndexes(t.Context(), "schema", "orders")
+		require.NoError(t, err)
+		assert.Equal(t, []IndexInfo{
+			{Name: "fk_user", Columns: []string{"user_id"}},
+			{Name: "id", Columns: []string{"id"}},
+		}, indexes)
+	})
+}
+
+func TestParseDDL_Mysqldump(t *testing.T) {
+	ddl := "-- MySQL dump 10.13\n" +
+		"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n" +
+		"CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 */;\n" +
+		"USE `shop`;\n" +
+		"DROP TABLE IF EXISTS `items`;\n" +
+		"CREATE TABLE `items` (\n" +
+		"  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `status` enum('active','it''s gone') COLLATE utf8mb4_bin DEFAULT 'active' COMMENT 'State; see docs',\n" +
+		"  `is_public` tinyint(1) NOT NULL DEFAULT '0',\n" +
+		"  `price` decimal(10, 2) DEFAULT NULL,\n" +
+		"  `tax_price` decimal(10,2) GENERATED ALWAYS AS ((`price` * 1.1)) VIRTUAL,\n" +
+		"  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+		"  `shop_id` bigint(20) NOT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY (`status`),\n" +
+		"  CONSTRAINT `fk_shop` FOREIGN KEY (`shop_id`) REFERENCES `shops` (`id`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4 COMMENT='Sold items';\n" +
+		"/*!50001 CREATE ALGORITHM=UNDEFINED */\n" +
+		"/*!50013 DEFINER=`root`@`%` SQL SECURITY DEFINER */\n" +
+		"/*!50001 VIEW `public_items` AS select `items`.`id` AS `id` from `items` where (`items`.`is_public` = 1) */;\n" +
+		"DELIMITER ;;\n" +
+		"/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`%`*/ /*!50003 TRIGGER `items_bi` BEFORE INSERT ON `items` FOR EACH ROW SET NEW.status = 'active'; */;;\n" +
+		"CREATE DEFINER=`root`@`%` FUNCTION `tax`(p decimal(10,2)) RETURNS decimal(10,2)\n" +
+		"    DETERMINISTIC\n" +
+		"BEGIN\n  RETURN p * 1.1;\nEND ;;\n" +
+		"DELIMITER ;\n" +
+		"ALTER TABLE `items` ADD UNIQUE KEY `uk_shop_status` (`shop_id`, `status`), ADD COLUMN `note` text;\n" +
+		"CREATE INDEX idx_created ON items (created_at DESC);\n"
+
+	snapshots, err := ParseDDL(ddl, "dump")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1, "the default database is not created when USE selects another")
+	snapshot := snapshots[0]
+	assert.Equal(t, "shop", snapshot.Database)
+
+	require.Len(t, snapshot.Tables, 1)
+	items := snapshot.Tables[0]
+	assert.Equal(t, "items", items.Name)
+	assert.Equal(t, "Sold items", items.Comment)
+	assert.Equal(t, []ColumnInfo{
+		{Name: "id", Type: "int unsigned", IsNullable: "NO"},
+		{Name: "status", Type: "enum('active','it''s gone')", IsNullable: "YES", Default: sql.NullString{String: "active", Valid: true}, Comment: "State; see docs"},
+		{Name: "is_public", Type: "tinyint(1)", IsNullable: "NO", Default: sql.NullString{String: "0", Valid: true}},
+		{Name: "price", Type: "decimal(10,2)", IsNullable: "YES"},
+		{Name: "tax_price", Type: "decimal(10,2)", IsNullable: "YES"},
+		{Name: "created_at", Type: "timestamp", IsNullable: "YES", Default: sql.NullString{String: "CURRENT_TIMESTAMP", Valid: true}},
+		{Name: "shop_id", Type: "bigint", IsNullable: "NO"},
+		{Name: "note", Type: "text", IsNullable: "YES"},
+	}, items.Columns)
+	assert.Equal(t, []string{"id"}, items.PrimaryKeys)
+	assert.Equal(t, []UniqueKey{{Name: "uk_shop_status", Columns: []string{"shop_id", "status"}}}, items.UniqueKeys)
+	assert.Equal(t, []ForeignKey{{Name: "fk_shop", Columns: []string{"shop_id"}, RefTable: "shops", RefColumns: []string{"id"}}}, items.ForeignKeys)
+	assert.Equal(t, []IndexInfo{
+		{Name: "idx_created", Columns: []string{"created_at"}},
+		{Name: "status", Columns: []string{"status"}},
+	}, items.Indexes, "the index created for fk_shop is named after the constraint and not listed")
+
+	assert.Equal(t, map[string][]GeneratedColumn{"items": {{Name: "tax_price", Expression: "(`price` * 1.1)"}}}, snapshot.GeneratedColumns)
+
+	require.Len(t, snapshot.Views, 1)
+	assert.Equal(t, "public_items", snapshot.Views[0].Name)
+	assert.Equal(t, "select `items`.`id` AS `id` from `items` where (`items`.`is_public` = 1)", snapshot.Views[0].Definition)
+
+	require.Len(t, snapshot.Triggers, 1)
+	assert.Equal(t, SchemaObject{Name: "items_bi", Type: "TRIGGER", Table: "items", Timing: "BEFORE", Event: "INSERT", Definition: "SET NEW.status = 'active';"}, snapshot.Triggers[0])
+
+	assert.Equal(t, []SchemaObject{{Name: "tax", Type: "FUNCTION", Definition: "BEGIN\n  RETURN p * 1.1;\nEND"}}, snapshot.Routines,
+		"only the body is kept, like INFORMATION_SCHEMA.ROUTINES.ROUTINE_DEFINITION")
+}
+
+func TestParseDDL_RoutineBody(t *testing.T) {
+	snapshots, err := ParseDDL(`
+DELIMITER ;;
+CREATE FUNCTION full_name(first VARCHAR(50), last VARCHAR(50)) RETURNS varchar(101) CHARSET utf8mb4 COLLATE utf8mb4_bin
+    NO SQL
+    DETERMINISTIC
+    COMMENT 'Joins the names'
+RETURN CONCAT(first, ' ', last) ;;
+CREATE DEFINER=CURRENT_USER PROCEDURE archive(IN before DATE)
+    MODIFIES SQL DATA
+    SQL SECURITY INVOKER
+BEGIN
+  DELETE FROM orders WHERE created_at < before;
+END ;;
+CREATE PROCEDURE noop() SELECT 1 ;;
+DELIMITER ;
+`, "app")
+	require.NoError(t, err)
+	assert.Equal(t, []SchemaObject{
+		{Name: "full_name", Type: "FUNCTION", Definition: "RETURN CONCAT(first, ' ', last)"},
+		{Name: "archive", Type: "PROCEDURE", Definition: "BEGIN\n  DELETE FROM orders WHERE created_at < before;\nEND"},
+		{Name: "noop", Type: "PROCEDURE", Definition: "SELECT 1"},
+	}, snapshots[0].Routines)
+}
+
+func TestParseDDL_Errors(t *testing.T) {
+	_, err := ParseDDL("CREATE TABLE t (id int COMMENT 'unterminated);", "db")
+	assert.EqualError(t, err, "unterminated '")
+
+	_, err = ParseDDL("CREATE TABLE t (id int, PRIMARY KEY (id);", "db")
+	assert.ErrorContains(t, err, "unbalanced parentheses")
+
+	_, err = ParseDDL("ALTER TABLE missing ADD COLUMN id int;", "db")
+	assert.EqualError(t, err, "ALTER TABLE missing: table is not defined")
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"sort"
+)
+
+// DependencyEdge is a foreign key of a table that creates a dependency on another table
+type DependencyEdge struct {
+	Table string     `json:"table"`
+	FK    ForeignKey `json:"foreignKey"`
+}
+
+// DependencyCycle is a group of tables that depend on each other through foreign keys
+type DependencyCycle struct {
+	Tables []string         `json:"tables"`
+	Edges  []DependencyEdge `json:"foreignKeys"`
+}
+
+// DependencyOrder holds FK-safe orderings of tables
+type DependencyOrder struct {
+	InsertionOrder []string          `json:"insertionOrder"` // Parents before children
+	DeletionOrder  []string          `json:"deletionOrder"`  // Children before parents
+	SelfReferences []DependencyEdge  `json:"selfReferences"`
+	Cycles         []DependencyCycle `json:"cycles"`
+}
+
+// CycleOf returns the 1-based number of the cycle that contains the table, or 0 if it is not part of a cycle
+func (o DependencyOrder) CycleOf(table string) int {
+	for i, c := range o.Cycles {
+		for _, t := range c.Tables {
+			if t == table {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// BuildDependencyOrder sorts tables so that referenced tables come before referencing tables.
+// Self-references are reported separately and do not affect the order. Tables in a cycle are
+// reported with the foreign keys that cause it and placed next to each other in name order.
+// Foreign keys to tables outside the list (e.g. another database) are ignored.
+func BuildDependencyOrder(tables []TableSummary) DependencyOrder {
+	order := DependencyOrder{
+		InsertionOrder: []string{},
+		DeletionOrder:  []string{},
+		SelfReferences: []DependencyEdge{},
+		Cycles:         []DependencyCycle{},
+	}
+
+	known := make(map[string]bool, len(tables))
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		known[t.Name] = true
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+
+	// parents[child] lists the tables that child references
+	parents := make(map[string][]string)
+	var edges []DependencyEdge
+	for _, t := range tables {
+		for _, fk := range t.FK {
+			if !known[fk.RefTable] {
+				continue
+			}
+			if fk.RefTable == t.Name {
+				order.SelfReferences = append(order.SelfReferences, DependencyEdge{Table: t.Name, FK: fk})
+				continue
+			}
+			parents[t.Name] = append(parents[t.Name], fk.RefTable)
+			edges = append(edges, DependencyEdge{Table: t.Name, FK: fk})
+		}
+	}
+
+	components := stronglyConnectedComponents(names, parents)
+
+	componentOf := make(map[string]int, len(names))
+	for i, c := range components {
+		for _, t := range c {
+			componentOf[t] = i
+		}
+	}
+	for i, c := range components {
+		if len(c) > 1 {
+			cycle := DependencyCycle{Tables: c}
+			for _, e := range edges {
+				if componentOf[e.Table] == i && componentOf[e.FK.RefTable] == i {
+					cycle.Edges = append(cycle.Edges, e)
+				}
+			}
+			order.Cycles = append(order.Cycles, cycle)
+		}
+	}
+
+	// Topologically sort the components, choosing the component with the smallest table name first
+	// among those ready so that the result is deterministic
+	waiting := make([]map[int]bool, len(components))
+	children := make([]map[int]bool, len(components))
+	for i := range components {
+		waiting[i] = make(map[int]bool)
+		children[i] = make(map[int]bool)
+	}
+	for child, ps := range parents {
+		for _, p := range ps {
+			c, pc := componentOf[child], componentOf[p]
+			if c != pc {
+				waiting[c][pc] = true
+				children[pc][c] = true
+			}
+		}
+	}
+
+	var ready []int
+	for i := range components {
+		if len(waiting[i]) == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(a, b int) bool { return components[ready[a]][0] < components[ready[b]][0] })
+		current := ready[0]
+		ready = ready[1:]
+
+		order.InsertionOrder = append(order.InsertionOrder, components[current]...)
+		for c := range children[current] {
+			delete(waiting[c], current)
+			if len(waiting[c]) == 0 {
+				ready = append(ready, c)
+			}
+		}
+	}
+
+	for i := len(order.InsertionOrder) - 1; i >= 0; i-- {
+		order.DeletionOrder = append(order.DeletionOrder, order.InsertionOrder[i])
+	}
+
+	sort.Slice(order.Cycles, func(a, b int) bool { return order.Cycles[a].Tables[0] < order.Cycles[b].Tables[0] })
+
+	return order
+}
+
+// stronglyConnectedComponents groups the tables into strongly connected components using Tarjan's algorithm.
+// Tables within each component are sorted by name.
+func stronglyConnectedComponents(names []string, parents map[string][]string) [][]string {
+	index := 0
+	indexes := make(map[string]int)
+	lowlinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(string)
+	visit = func(v string) {
+		indexes[v] = index
+		lowlinks[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range parents[v] {
+			if _, ok := indexes[w]; !ok {
+				visit(w)
+				lowlinks[v] = min(lowlinks[v], lowlinks[w])
+			} else if onStack[w] {
+				lowlinks[v] = min(lowlinks[v], indexes[w])
+			}
+		}
+
+		if lowlinks[v] == indexes[v] {
+			var component []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := indexes[name]; !ok {
+			visit(name)
+		}
+	}
+
+	return components
+}
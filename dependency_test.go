@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDependencyOrder(t *testing.T) {
+	tables := []TableSummary{
+		{Name: "order_items", FK: []ForeignKey{
+			{Name: "fk_order", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}},
+			{Name: "fk_product", Columns: []string{"product_id"}, RefTable: "products", RefColumns: []string{"id"}},
+		}},
+		{Name: "orders", FK: []ForeignKey{
+			{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+		}},
+		{Name: "products", FK: []ForeignKey{
+			{Name: "fk_parent", Columns: []string{"parent_id"}, RefTable: "products", RefColumns: []string{"id"}},
+			{Name: "fk_customer", Columns: []string{"customer_id"}, RefTable: "crm.customers", RefColumns: []string{"id"}, Origin: FKOriginVirtual},
+		}},
+		{Name: "teams", FK: []ForeignKey{
+			{Name: "fk_leader", Columns: []string{"leader_id"}, RefTable: "users", RefColumns: []string{"id"}},
+		}},
+		{Name: "users", FK: []ForeignKey{
+			{Name: "fk_team", Columns: []string{"team_id"}, RefTable: "teams", RefColumns: []string{"id"}},
+		}},
+	}
+
+	order := BuildDependencyOrder(tables)
+
+	assert.Equal(t, []string{"products", "teams", "users", "orders", "order_items"}, order.InsertionOrder)
+	assert.Equal(t, []string{"order_items", "orders", "users", "teams", "products"}, order.DeletionOrder)
+	assert.Equal(t, []DependencyEdge{{Table: "products", FK: tables[2].FK[0]}}, order.SelfReferences)
+	assert.Equal(t, []DependencyCycle{
+		{
+			Tables: []string{"teams", "users"},
+			Edges: []DependencyEdge{
+				{Table: "teams", FK: tables[3].FK[0]},
+				{Table: "users", FK: tables[4].FK[0]},
+			},
+		},
+	}, order.Cycles)
+	assert.Equal(t, 1, order.CycleOf("users"))
+	assert.Equal(t, 0, order.CycleOf("orders"))
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SchemaDiff holds the differences between the tables of two schemas. Changes are reported from the target
+// to the source: added objects exist only in the source, removed objects exist only in the target
+type SchemaDiff struct {
+	Source        string        `json:"source"`
+	Target        string        `json:"target"`
+	TargetFile    string        `json:"targetFile,omitempty"` // The snapshot file of the target, when it is not a live database
+	AddedTables   []TableDetail `json:"addedTables"`
+	RemovedTables []TableDetail `json:"removedTables"`
+	ChangedTables []TableDiff   `json:"changedTables"`
+
+	keptForeignKeys []keptForeignKey // Declared foreign keys in both schemas, which a migration may have to re-create
+}
+
+// keptForeignKey is a declared foreign key of a table that is the same in both schemas
+type keptForeignKey struct {
+	Table string
+	ForeignKey
+}
+
+// IsEmpty reports whether the schemas have no differences
+func (d SchemaDiff) IsEmpty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 && len(d.ChangedTables) == 0
+}
+
+// TableDiff holds the differences of a table that exists in both schemas.
+// A key or index whose definition changed is reported as removed and added
+type TableDiff struct {
+	Name               string         `json:"name"`
+	Comment            *ValueChange   `json:"comment,omitempty"`
+	AddedColumns       []ColumnInfo   `json:"addedColumns"`
+	RemovedColumns     []ColumnInfo   `json:"removedColumns"`
+	ChangedColumns     []ColumnChange `json:"changedColumns"`
+	PrimaryKey         *KeyChange     `json:"primaryKey,omitempty"`
+	AddedUniqueKeys    []UniqueKey    `json:"addedUniqueKeys"`
+	RemovedUniqueKeys  []UniqueKey    `json:"removedUniqueKeys"`
+	AddedIndexes       []IndexInfo    `json:"addedIndexes"`
+	RemovedIndexes     []IndexInfo    `json:"removedIndexes"`
+	AddedForeignKeys   []ForeignKey   `json:"addedForeignKeys"`
+	RemovedForeignKeys []ForeignKey   `json:"removedForeignKeys"`
+}
+
+// ValueChange is a value that differs between the target and the source
+type ValueChange struct {
+	Target string `json:"target"`
+	Source string `json:"source"`
+}
+
+// KeyChange is a primary key that differs between the target and the source
+type KeyChange struct {
+	Target []string `json:"target"`
+	Source []string `json:"source"`
+}
+
+// ColumnChange is a column whose definition differs between the target and the source
+type ColumnChange struct {
+	Name    string     `json:"name"`
+	Target  ColumnInfo `json:"target"`
+	Source  ColumnInfo `json:"source"`
+	Changes []string   `json:"changes"` // Changed attributes: type, nullable, default or comment
+}
+
+// DiffSchemas compares the tables of two snapshots. Only declared foreign keys are compared,
+// since virtual and inferred relationships are not part of the schema
+func DiffSchemas(source, target SchemaSnapshot) SchemaDiff {
+	diff := SchemaDiff{
+		Source:        source.Database,
+		Target:        target.Database,
+		AddedTables:   []TableDetail{},
+		RemovedTables: []TableDetail{},
+		ChangedTables: []TableDiff{},
+	}
+
+	for _, s := range source.Tables {
+		i := slices.IndexFunc(target.Tables, func(t TableDetail) bool { return t.Name == s.Name })
+		if i < 0 {
+			s.ForeignKeys = emptyIfNil(declaredForeignKeys(s.ForeignKeys))
+			diff.AddedTables = append(diff.AddedTables, s)
+			continue
+		}
+		if d := diffTable(s, target.Tables[i]); !d.isEmpty() {
+			diff.ChangedTables = append(diff.ChangedTables, d)
+		}
+		for _, fk := range declaredForeignKeys(s.ForeignKeys) {
+			if slices.ContainsFunc(declaredForeignKeys(target.Tables[i].ForeignKeys), func(t ForeignKey) bool { return sameForeignKey(fk, t) }) {
+				diff.keptForeignKeys = append(diff.keptForeignKeys, keptForeignKey{Table: s.Name, ForeignKey: fk})
+			}
+		}
+	}
+	for _, t := range target.Tables {
+		if !slices.ContainsFunc(source.Tables, func(s TableDetail) bool { return s.Name == t.Name }) {
+			t.ForeignKeys = emptyIfNil(declaredForeignKeys(t.ForeignKeys))
+			diff.RemovedTables = append(diff.RemovedTables, t)
+		}
+	}
+
+	return diff
+}
+
+// diffTable compares a table that exists in both schemas
+func diffTable(source, target TableDetail) TableDiff {
+	d := TableDiff{Name: source.Name}
+
+	if source.Comment != target.Comment {
+		d.Comment = &ValueChange{Target: target.Comment, Source: source.Comment}
+	}
+
+	for _, s := range source.Columns {
+		i := slices.IndexFunc(target.Columns, func(t ColumnInfo) bool { return strings.EqualFold(t.Name, s.Name) })
+		if i < 0 {
+			d.AddedColumns = append(d.AddedColumns, s)
+			continue
+		}
+		if changes := columnChanges(s, target.Columns[i]); len(changes) > 0 {
+			d.ChangedColumns = append(d.ChangedColumns, ColumnChange{Name: s.Name, Target: target.Columns[i], Source: s, Changes: changes})
+		}
+	}
+	for _, t := range target.Columns {
+		if !slices.ContainsFunc(source.Columns, func(s ColumnInfo) bool { return strings.EqualFold(s.Name, t.Name) }) {
+			d.RemovedColumns = append(d.RemovedColumns, t)
+		}
+	}
+
+	if !slices.Equal(source.PrimaryKeys, target.PrimaryKeys) {
+		d.PrimaryKey = &KeyChange{Target: emptyIfNil(target.PrimaryKeys), Source: emptyIfNil(source.PrimaryKeys)}
+	}
+
+	sameUniqueKey := func(a, b UniqueKey) bool { return a.Name == b.Name && slices.Equal(a.Columns, b.Columns) }
+	d.AddedUniqueKeys, d.RemovedUniqueKeys = diffByEquality(source.UniqueKeys, target.UniqueKeys, sameUniqueKey)
+
+	sameIndex := func(a, b IndexInfo) bool {
+		return a.Name == b.Name && a.Unique == b.Unique && slices.Equal(a.Columns, b.Columns)
+	}
+	d.AddedIndexes, d.RemovedIndexes = diffByEquality(source.Indexes, target.Indexes, sameIndex)
+
+	d.AddedForeignKeys, d.RemovedForeignKeys = diffByEquality(
+		declaredForeignKeys(source.ForeignKeys), declaredForeignKeys(target.ForeignKeys), sameForeignKey)
+
+	d.AddedColumns, d.RemovedColumns, d.ChangedColumns = emptyIfNil(d.AddedColumns), emptyIfNil(d.RemovedColumns), emptyIfNil(d.ChangedColumns)
+	return d
+}
+
+// sameForeignKey reports whether two foreign keys have the same definition
+func sameForeignKey(a, b ForeignKey) bool {
+	return a.Name == b.Name && a.RefTable == b.RefTable && slices.Equal(a.Columns, b.Columns) && slices.Equal(a.RefColumns, b.RefColumns)
+}
+
+// isEmpty reports whether the table has no differences
+func (d TableDiff) isEmpty() bool {
+	return d.Comment == nil && d.PrimaryKey == nil &&
+		len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 && len(d.ChangedColumns) == 0 &&
+		len(d.AddedUniqueKeys) == 0 && len(d.RemovedUniqueKeys) == 0 &&
+		len(d.AddedIndexes) == 0 && len(d.RemovedIndexes) == 0 &&
+		len(d.AddedForeignKeys) == 0 && len(d.RemovedForeignKeys) == 0
+}
+
+// columnChanges returns the attributes of the column that differ
+func columnChanges(source, target ColumnInfo) []string {
+	var changes []string
+	if !strings.EqualFold(source.Type, target.Type) {
+		changes = append(changes, "type")
+	}
+	if source.IsNullable != target.IsNullable {
+		changes = append(changes, "nullable")
+	}
+	if source.Default != target.Default {
+		changes = append(changes, "default")
+	}
+	if source.Comment != target.Comment {
+		changes = append(changes, "comment")
+	}
+	return changes
+}
+
+// diffByEquality returns the elements that are only in source (added) and only in target (removed)
+func diffByEquality[T any](source, target []T, equal func(a, b T) bool) (added, removed []T) {
+	added, removed = []T{}, []T{}
+	for _, s := range source {
+		if !slices.ContainsFunc(target, func(t T) bool { return equal(s, t) }) {
+			added = append(added, s)
+		}
+	}
+	for _, t := range target {
+		if !slices.ContainsFunc(source, func(s T) bool { return equal(s, t) }) {
+			removed = append(removed, t)
+		}
+	}
+	return added, removed
+}
+
+// DiffDatabases fetches the schemas of two databases and compares them
+func (h *Handler) DiffDatabases(ctx context.Context, sourceDBName, targetDBName string) (SchemaDiff, error) {
+	source, err := h.FetchSnapshot(ctx, sourceDBName)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	target, err := h.FetchSnapshot(ctx, targetDBName)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	return DiffSchemas(source, target), nil
+}
+
+// DiffSnapshotFile fetches the schema of a database and compares it with a snapshot file written by
+// `dump --format json` (or a DDL file), which is the target
+func (h *Handler) DiffSnapshotFile(ctx context.Context, sourceDBName, path string) (SchemaDiff, error) {
+	target, err := loadSnapshotFile(path, sourceDBName)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	if target, err = h.access.visibleSnapshot(ctx, target); err != nil {
+		return SchemaDiff{}, err
+	}
+	source, err := h.FetchSnapshot(ctx, sourceDBName)
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+
+	diff := DiffSchemas(source, target)
+	diff.TargetFile = path
+	return diff, nil
+}
+
+// snapshotPath resolves a snapshot path given by a client, which is relative to the snapshot directory.
+// Clients can't read files outside the directory, so that the server can't be used to probe its file system
+func (h *Handler) snapshotPath(path string) (string, error) {
+	if h.snapshotDir == "" {
+		return "", fmt.Errorf("targetSnapshot is not available: the server has no snapshot directory (SNAPSHOT_DIR)")
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" && ext != ".sql" {
+		return "", fmt.Errorf("targetSnapshot must be a .json or .sql file: %s", path)
+	}
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("targetSnapshot must be a path relative to the snapshot directory: %s", path)
+	}
+
+	dir, err := filepath.Abs(h.snapshotDir)
+	if err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the snapshot directory: %w", err)
+	}
+	// Symbolic links must not lead out of the directory either
+	resolved, err := filepath.EvalSymlinks(filepath.Join(dir, path))
+	if err != nil {
+		return "", fmt.Errorf("snapshot %s does not exist in the snapshot directory", path)
+	}
+	if rel, err := filepath.Rel(dir, resolved); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("targetSnapshot must be in the snapshot directory: %s", path)
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("snapshot %s is not a file", path)
+	}
+	return resolved, nil
+}
+
+// loadSnapshotFile loads the snapshot in the file. When the file holds more than one database, such as
+// a DDL file with USE statements, the snapshot of dbName is used
+func loadSnapshotFile(path string, dbName string) (SchemaSnapshot, error) {
+	snapshots, err := LoadSnapshots(path)
+	if err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("failed to load schema snapshot: %w", err)
+	}
+	if len(snapshots) == 1 {
+		return snapshots[0], nil
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Database == dbName {
+			return snapshot, nil
+		}
+	}
+	return SchemaSnapshot{}, fmt.Errorf("%s has no snapshot of database %s", path, dbName)
+}
+
+// errSchemasDiffer is returned by the diff subcommand with --exit-code when differences are found
+var errSchemasDiffer = errors.New("schemas differ")
+
+// runDiff implements the diff subcommand, which compares the schemas of two databases and writes the result to stdout:
+//
+//	mysql-schema-explorer-mcp diff --source app_staging --target app_production
+//	mysql-schema-explorer-mcp diff --source app_production --target-snapshot schema.json --exit-code
+func runDiff(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	source := flags.String("source", "", "The database to compare from")
+	target := flags.String("target", "", "The database to compare against (default: the fixed database of the connection, e.g. DB_NAME)")
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "The config file (default: CONFIG_FILE)")
+	connection := flags.String("connection", "", "The connection to compare on when several connections are configured (default: the first one)")
+	targetSnapshot := flags.String("target-snapshot", "", "A snapshot file written by `dump --format json` to compare against instead of a database")
+	format := flags.String("format", formatText, "The output format: text, json or ddl (a migration draft bringing the target in line with the source)")
+	exitCode := flags.Bool("exit-code", false, "Exit with status 1 when the schemas differ")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	if *source == "" {
+		return fmt.Errorf("--source is required")
+	}
+	if !slices.Contains([]string{formatText, formatJSON, formatDDL}, *format) {
+		return fmt.Errorf("--format must be %q, %q or %q", formatText, formatJSON, formatDDL)
+	}
+
+	config, err := readConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *target == "" {
+		if conn, ok := config.connection(*connection); ok {
+			*target = conn.Database
+		}
+	}
+	if *target == "" && *targetSnapshot == "" {
+		return fmt.Errorf("--target or --target-snapshot is required when the connection has no fixed database (DB_NAME)")
+	}
+	if *targetSnapshot == "" && *target == *source {
+		return fmt.Errorf("--source and --target are both %s; compare it with another database or --target-snapshot", *source)
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	connections, closeDB, err := setupConnections(config)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	handler, err := connections.Handler(*connection)
+	if err != nil {
+		return err
+	}
+
+	var diff SchemaDiff
+	if *targetSnapshot != "" {
+		diff, err = handler.DiffSnapshotFile(context.Background(), *source, *targetSnapshot)
+	} else {
+		diff, err = handler.DiffDatabases(context.Background(), *source, *target)
+	}
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	switch *format {
+	case formatJSON:
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		output.Write(append(data, '\n'))
+	case formatDDL:
+		output.WriteString(formatMigration(diff, BuildMigration(diff)))
+	default:
+		if err := handler.templates.SchemaDiff.Execute(&output, diff); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+	}
+
+	if _, err := stdout.Write(output.Bytes()); err != nil {
+		return err
+	}
+	if *exitCode && !diff.IsEmpty() {
+		return errSchemasDiffer
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	source := SchemaSnapshot{Database: "staging", Tables: []TableDetail{
+		{
+			Name: "orders", Comment: "Orders",
+			Columns: []ColumnInfo{
+				{Name: "id", Type: "bigint", IsNullable: "NO"},
+				{Name: "status", Type: "varchar(20)", IsNullable: "NO", Default: sql.NullString{String: "new", Valid: true}},
+				{Name: "user_id", Type: "int", IsNullable: "NO"},
+			},
+			PrimaryKeys: []string{"id"},
+			ForeignKeys: []ForeignKey{
+				{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+				{Name: "shop_id", Columns: []string{"shop_id"}, RefTable: "shops", RefColumns: []string{"id"}, Origin: FKOriginInferred, Confidence: 0.9},
+			},
+			Indexes: []IndexInfo{{Name: "idx_status", Columns: []string{"status", "id"}}},
+		},
+		{Name: "users", Comment: "Users"},
+	}}
+	target := SchemaSnapshot{Database: "production", Tables: []TableDetail{
+		{
+			Name: "orders", Comment: "Order header",
+			Columns: []ColumnInfo{
+				{Name: "id", Type: "int", IsNullable: "NO"},
+				{Name: "status", Type: "varchar(20)", IsNullable: "NO", Default: sql.NullString{String: "new", Valid: true}},
+				{Name: "note", Type: "text", IsNullable: "YES"},
+			},
+			PrimaryKeys: []string{"id"},
+			Indexes:     []IndexInfo{{Name: "idx_status", Columns: []string{"status"}}},
+		},
+		{Name: "logs"},
+		{Name: "users", Comment: "Users"},
+	}}
+
+	diff := DiffSchemas(source, target)
+
+	assert.Equal(t, "staging", diff.Source)
+	assert.Equal(t, "production", diff.Target)
+	assert.Empty(t, diff.AddedTables)
+	require.Len(t, diff.RemovedTables, 1)
+	assert.Equal(t, "logs", diff.RemovedTables[0].Name)
+
+	require.Len(t, diff.ChangedTables, 1, "unchanged tables are not reported")
+	orders := diff.ChangedTables[0]
+	assert.Equal(t, &ValueChange{Target: "Order header", Source: "Orders"}, orders.Comment)
+	assert.Equal(t, []ColumnInfo{{Name: "user_id", Type: "int", IsNullable: "NO"}}, orders.AddedColumns)
+	assert.Equal(t, []ColumnInfo{{Name: "note", Type: "text", IsNullable: "YES"}}, orders.RemovedColumns)
+	require.Len(t, orders.ChangedColumns, 1)
+	assert.Equal(t, "id", orders.ChangedColumns[0].Name)
+	assert.Equal(t, []string{"type"}, orders.ChangedColumns[0].Changes)
+	assert.Nil(t, orders.PrimaryKey)
+	assert.Equal(t, []IndexInfo{{Name: "idx_status", Columns: []string{"status", "id"}}}, orders.AddedIndexes)
+	assert.Equal(t, []IndexInfo{{Name: "idx_status", Columns: []string{"status"}}}, orders.RemovedIndexes)
+	assert.Equal(t, []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}, orders.AddedForeignKeys,
+		"inferred relationships are not compared")
+	assert.Empty(t, orders.RemovedForeignKeys)
+
+	assert.True(t, DiffSchemas(source, source).IsEmpty())
+}
+
+func TestHandler_DiffSchemas(t *testing.T) {
+	staging, err := ParseDDL(`
+CREATE TABLE users (
+    id INT PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE COMMENT 'Email address'
+) COMMENT='User information';
+CREATE TABLE orders (
+    id BIGINT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'new',
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)
+) COMMENT='Order header';
+CREATE TABLE coupons (id INT PRIMARY KEY) COMMENT='Coupons';
+`, "app_staging")
+	require.NoError(t, err)
+	production, err := ParseDDL(`
+CREATE TABLE users (
+    id INT PRIMARY KEY,
+    email VARCHAR(191) NOT NULL COMMENT 'Email address'
+) COMMENT='User information';
+CREATE TABLE orders (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL,
+    note TEXT
+) COMMENT='Order header';
+CREATE TABLE legacy_logs (id INT PRIMARY KEY);
+`, "app_production")
+	require.NoError(t, err)
+
+	db, err := NewSnapshotDB(append(staging, production...))
+	require.NoError(t, err)
+	handler := NewHandler(db, "")
+
+	t.Run("text", func(t *testing.T) {
+		result, err := handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName": "app_staging",
+				"targetDbName": "app_production",
+			}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		assert.Equal(t, `Schema diff of database "app_production" (target) against "app_staging" (source)
+* + exists only in the source, - exists only in the target, ~ differs (target -> source)
+* A key or index whose definition changed is shown as removed and added
+
+## Added Tables
++ coupons - Coupons
+
+## Removed Tables
+- legacy_logs
+
+## Changed Table: orders
++ column status: varchar(20) NOT NULL DEFAULT new
+- column note: text NULL
+~ column id: int NOT NULL -> bigint NOT NULL
++ foreign key fk_user: user_id -> users.id
+
+## Changed Table: users
+~ column email: varchar(191) NOT NULL [Email address] -> varchar(255) NOT NULL [Email address]
++ unique key email: email
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("same database", func(t *testing.T) {
+		result, err := handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName": "app_staging",
+				"targetDbName": "app_staging",
+			}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "sourceDbName and targetDbName are both app_staging; compare it with another database or targetSnapshot", result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		result, err := handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName": "app_staging",
+				"targetDbName": "app_production",
+				"format":       "json",
+			}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		diff, ok := result.StructuredContent.(SchemaDiff)
+		require.True(t, ok)
+		assert.Len(t, diff.ChangedTables, 2)
+	})
+
+	t.Run("ddl", func(t *testing.T) {
+		result, err := handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName": "app_staging",
+				"targetDbName": "app_production",
+				"format":       "ddl",
+			}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "-- Migration draft to bring `app_production` in line with `app_staging`\n")
+		assert.Contains(t, text, "\nALTER TABLE `orders` ADD CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);\n")
+		assert.Contains(t, text, "\n-- DESTRUCTIVE: drops the table and all of its data\nDROP TABLE `legacy_logs`;\n")
+	})
+
+	snapshotDir := t.TempDir()
+	data, err := json.Marshal(production[0])
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(snapshotDir, "release.json"), data, 0o644))
+	snapshotHandler := NewHandler(db, "", WithSnapshotDir(snapshotDir))
+
+	t.Run("snapshot", func(t *testing.T) {
+		result, err := snapshotHandler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName":   "app_staging",
+				"targetSnapshot": "release.json",
+				"format":         "json",
+			}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		diff := result.StructuredContent.(SchemaDiff)
+		assert.Equal(t, "app_production", diff.Target)
+		assert.Equal(t, "release.json", diff.TargetFile)
+		databaseDiff, err := handler.DiffDatabases(t.Context(), "app_staging", "app_production")
+		require.NoError(t, err)
+		diff.TargetFile = ""
+		expected, err := json.Marshal(databaseDiff)
+		require.NoError(t, err)
+		actual, err := json.Marshal(diff)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual), "a snapshot gives the same result as the database it was taken from")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		result, err := handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName":   "app_staging",
+				"targetDbName":   "app_production",
+				"targetSnapshot": "release.json",
+			}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		result, err = handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName":   "app_staging",
+				"targetSnapshot": "release.json",
+			}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "the server has no snapshot directory", "snapshots are only read from the snapshot directory")
+
+		result, err = handler.DiffSchemas(t.Context(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		result, err = handler.DiffSchemas(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"sourceDbName": "app_qa",
+				"targetDbName": "app_production",
+			}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "database app_qa is not in the snapshot")
+	})
+}
+
+func TestHandler_SnapshotPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "snapshots")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "releases"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "releases", "v1.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(root, "secret.json"), filepath.Join(dir, "link.json")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dir.json"), 0o755))
+	handler := NewHandler(nil, "", WithSnapshotDir(dir))
+
+	path, err := handler.snapshotPath("releases/v1.json")
+	require.NoError(t, err)
+	resolved, err := filepath.EvalSymlinks(filepath.Join(dir, "releases", "v1.json"))
+	require.NoError(t, err)
+	assert.Equal(t, resolved, path)
+
+	tests := []struct {
+		name     string
+		path     string
+		errorMsg string
+	}{
+		{name: "parent directory", path: "../secret.json", errorMsg: "must be a path relative to the snapshot directory"},
+		{name: "parent directory in the middle", path: "releases/../../secret.json", errorMsg: "must be a path relative to the snapshot directory"},
+		{name: "absolute path", path: filepath.Join(root, "secret.json"), errorMsg: "must be a path relative to the snapshot directory"},
+		{name: "absolute path in the directory", path: filepath.Join(dir, "releases", "v1.json"), errorMsg: "must be a path relative to the snapshot directory"},
+		{name: "symbolic link out of the directory", path: "link.json", errorMsg: "must be in the snapshot directory"},
+		{name: "other extension", path: "../../etc/passwd", errorMsg: "must be a .json or .sql file"},
+		{name: "missing file", path: "missing.json", errorMsg: "snapshot missing.json does not exist in the snapshot directory"},
+		{name: "directory", path: "dir.json", errorMsg: "snapshot dir.json is not a file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handler.snapshotPath(tt.path)
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+
+	_, err = NewHandler(nil, "").snapshotPath("releases/v1.json")
+	assert.ErrorContains(t, err, "the server has no snapshot directory")
+}
+
+func TestHandler_DiffSchemas_FixedDatabase(t *testing.T) {
+	snapshots, err := ParseDDL(`
+CREATE DATABASE app;
+USE app;
+CREATE TABLE users (id INT PRIMARY KEY);
+CREATE DATABASE other;
+USE other;
+CREATE TABLE salaries (id INT PRIMARY KEY);
+`, "app")
+	require.NoError(t, err)
+	db, err := NewSnapshotDB(snapshots)
+	require.NoError(t, err)
+	snapshotDir := t.TempDir()
+	handler := NewHandler(db, "app", WithSnapshotDir(snapshotDir))
+
+	diffSchemas := func(args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler.DiffSchemas(t.Context(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: args}})
+		require.NoError(t, err)
+		return result
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"sourceDbName": "other"},
+		{"sourceDbName": "app", "targetDbName": "other"},
+		{"sourceDbName": "other", "targetSnapshot": "app.json"},
+	} {
+		result := diffSchemas(args)
+		assert.True(t, result.IsError, "%v", args)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "must be app, the fixed database")
+	}
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"targetDbName": "app"},
+		{"sourceDbName": "app", "targetDbName": "app"},
+	} {
+		result := diffSchemas(args)
+		assert.True(t, result.IsError, "%v", args)
+		assert.Equal(t, "sourceDbName and targetDbName are both app; compare it with another database or targetSnapshot", result.Content[0].(mcp.TextContent).Text)
+	}
+
+	data, err := json.Marshal(snapshots[0])
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(snapshotDir, "app.json"), data, 0o644))
+	result := diffSchemas(map[string]interface{}{"targetSnapshot": "app.json"})
+	require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "No differences.", "the fixed database is the default source")
+}
+
+func TestRunDiff_InvalidArguments(t *testing.T) {
+	t.Setenv("DB_NAME", "")
+
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{name: "no source", args: []string{"--target", "app_production"}, errorMsg: "--source is required"},
+		{name: "no target", args: []string{"--source", "app_staging"}, errorMsg: "--target or --target-snapshot is required"},
+		{name: "unknown format", args: []string{"--source", "a", "--target", "b", "--format", "yaml"}, errorMsg: "--format must be"},
+		{name: "unexpected argument", args: []string{"--source", "a", "--target", "b", "c"}, errorMsg: "unexpected arguments: c"},
+		{name: "same source and target", args: []string{"--source", "a", "--target", "a"}, errorMsg: "--source and --target are both a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDiff(tt.args, io.Discard)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestRunDiff_ExitCode(t *testing.T) {
+	t.Setenv("SCHEMA_SNAPSHOT", "testdata/schema.sql")
+	t.Setenv("DB_NAME", "")
+
+	var output bytes.Buffer
+	err := runDiff([]string{"--source", "schema", "--target-snapshot", "testdata/schema.sql", "--exit-code"}, &output)
+	assert.NoError(t, err, "identical schemas")
+	assert.Contains(t, output.String(), "No differences.")
+
+	output.Reset()
+	err = runDiff([]string{"--source", "schema", "--target-snapshot", "testdata/schema_impact.sql", "--exit-code"}, &output)
+	assert.ErrorIs(t, err, errSchemasDiffer)
+	assert.Contains(t, output.String(), "## ")
+
+	output.Reset()
+	err = runDiff([]string{"--source", "schema", "--target-snapshot", "testdata/schema_impact.sql"}, &output)
+	assert.NoError(t, err, "differences are not an error without --exit-code")
+	assert.NotEmpty(t, output.String())
+}
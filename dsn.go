@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// TLS modes of DBConfig, which are the values of the tls parameter of go-sql-driver/mysql
+const (
+	dbTLSDisabled   = "false"
+	dbTLSVerify     = "true"
+	dbTLSSkipVerify = "skip-verify"
+	dbTLSPreferred  = "preferred" // TLS when the server supports it, without verifying the certificate
+)
+
+var dbTLSModes = []string{dbTLSDisabled, dbTLSVerify, dbTLSSkipVerify, dbTLSPreferred}
+
+// Validate checks the settings that can be checked without reading files or connecting
+func (c DBConfig) Validate() error {
+	if c.DSN != "" {
+		if _, err := mysql.ParseDSN(c.DSN); err != nil {
+			return fmt.Errorf("invalid dsn: %w", err)
+		}
+	}
+	if c.TLS != "" && !slices.Contains(dbTLSModes, c.TLS) {
+		return fmt.Errorf("tls must be one of %s: %s", strings.Join(dbTLSModes, ", "), c.TLS)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("tlsCert and tlsKey must be set together")
+	}
+	if c.TLS == dbTLSDisabled && (c.TLSCA != "" || c.TLSCert != "") {
+		return fmt.Errorf("tlsCA and tlsCert can't be used when tls is false")
+	}
+	passwords := 0
+	for _, source := range []string{c.Password, c.PasswordFile, c.PasswordCommand} {
+		if source != "" {
+			passwords++
+		}
+	}
+	if passwords > 1 {
+		return fmt.Errorf("only one of password, passwordFile and passwordCommand can be set")
+	}
+	if c.ConnectTimeout < 0 || c.ReadTimeout < 0 {
+		return fmt.Errorf("connectTimeout and readTimeout must not be negative")
+	}
+	if strings.ContainsAny(c.TimeZone, `'\`) {
+		return fmt.Errorf("invalid timeZone: %s", c.TimeZone)
+	}
+	return nil
+}
+
+// mysqlConfig builds the driver configuration. The settings are applied over DSN, so that a DSN can carry the
+// driver options that have no setting of their own, and passwords don't need to be escaped. The option file
+// only fills in the settings that are not set
+func (c DBConfig) mysqlConfig() (*mysql.Config, error) {
+	c, err := c.withOptionFile()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := mysql.NewConfig()
+	if c.DSN != "" {
+		parsed, err := mysql.ParseDSN(c.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dsn: %w", err)
+		}
+		cfg = parsed
+	}
+
+	if c.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = c.Socket
+	} else if c.Host != "" || c.Port != "" {
+		host, port := c.Host, c.Port
+		if host == "" {
+			host = "localhost"
+		}
+		if port == "" {
+			port = "3306"
+		}
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, port)
+	}
+	if c.User != "" {
+		cfg.User = c.User
+	}
+	if c.Password != "" {
+		cfg.Passwd = c.Password
+	}
+	if c.hasPasswordSource() {
+		if err := cfg.Apply(c.passwordBeforeConnect()); err != nil {
+			return nil, err
+		}
+	}
+	if c.ConnectTimeout != 0 {
+		cfg.Timeout = c.ConnectTimeout
+	}
+	if c.ReadTimeout != 0 {
+		cfg.ReadTimeout = c.ReadTimeout
+	}
+	if c.Charset != "" {
+		if err := cfg.Apply(mysql.Charset(c.Charset, "")); err != nil {
+			return nil, err
+		}
+	}
+	if c.TimeZone != "" {
+		if cfg.Params == nil {
+			cfg.Params = map[string]string{}
+		}
+		// The driver runs SET time_zone=<value> on each new connection
+		cfg.Params["time_zone"] = "'" + c.TimeZone + "'"
+	}
+
+	if err := c.applyTLS(cfg); err != nil {
+		return nil, err
+	}
+
+	if c.ServerPublicKey != "" {
+		pubKey, err := loadServerPublicKey(c.ServerPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		// The driver only accepts public keys registered by name
+		name := "file:" + c.ServerPublicKey
+		mysql.RegisterServerPubKey(name, pubKey)
+		cfg.ServerPubKey = name
+	}
+	return cfg, nil
+}
+
+// applyTLS sets the TLS mode, and builds the TLS configuration when a CA or a client certificate is given
+func (c DBConfig) applyTLS(cfg *mysql.Config) error {
+	if c.TLSCA == "" && c.TLSCert == "" {
+		if c.TLS != "" {
+			cfg.TLSConfig = c.TLS
+			cfg.TLS = nil
+		}
+		return nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.TLS == dbTLSSkipVerify}
+	if c.TLSCA != "" {
+		pemData, err := os.ReadFile(c.TLSCA)
+		if err != nil {
+			return fmt.Errorf("failed to read the TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return fmt.Errorf("no certificates found in %s", c.TLSCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+		if err != nil {
+			return fmt.Errorf("failed to load the TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	cfg.TLS = tlsConfig
+	cfg.AllowFallbackToPlaintext = c.TLS == dbTLSPreferred
+	return nil
+}
+
+// loadServerPublicKey loads an RSA public key in PEM format, as written by
+// SELECT VARIABLE_VALUE FROM performance_schema.global_status WHERE VARIABLE_NAME = 'Caching_sha2_password_rsa_public_key'
+func loadServerPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the server public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the server public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the server public key in %s is not an RSA key", path)
+	}
+	return rsaKey, nil
+}
+
+// maskDSN replaces the password of the DSN for display
+func maskDSN(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return maskedSecret
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = maskedSecret
+	}
+	return cfg.FormatDSN()
+}
+
+func connectDB(config DBConfig) (*sql.DB, error) {
+	cfg, err := config.mysqlConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(connector), nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key in PEM format, and returns their paths
+func writeTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certPath, keyPath
+}
+
+func TestDBConfig_MySQLConfig(t *testing.T) {
+	t.Run("settings", func(t *testing.T) {
+		cfg, err := DBConfig{
+			Host:           "db.internal",
+			Port:           "3307",
+			User:           "app",
+			Password:       "p@ss/word:1",
+			ConnectTimeout: 5 * time.Second,
+			ReadTimeout:    30 * time.Second,
+			Charset:        "utf8mb4",
+			TimeZone:       "+00:00",
+		}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "tcp", cfg.Net)
+		assert.Equal(t, "db.internal:3307", cfg.Addr)
+		assert.Equal(t, "p@ss/word:1", cfg.Passwd, "passwords are not parsed as a DSN")
+		assert.Equal(t, 5*time.Second, cfg.Timeout)
+		assert.Equal(t, 30*time.Second, cfg.ReadTimeout)
+		assert.Equal(t, "'+00:00'", cfg.Params["time_zone"])
+		assert.Contains(t, cfg.FormatDSN(), "charset=utf8mb4")
+	})
+
+	t.Run("unix socket", func(t *testing.T) {
+		cfg, err := DBConfig{Host: "db.internal", Socket: "/var/run/mysqld/mysqld.sock", User: "app"}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "unix", cfg.Net)
+		assert.Equal(t, "/var/run/mysqld/mysqld.sock", cfg.Addr, "the socket takes precedence over the host")
+
+		cfg, err = DBConfig{DSN: "app@tcp(db.internal:3306)/", Socket: "/tmp/mysql.sock"}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "unix", cfg.Net)
+		assert.Equal(t, "/tmp/mysql.sock", cfg.Addr)
+	})
+
+	t.Run("dsn", func(t *testing.T) {
+		cfg, err := DBConfig{DSN: "app:secret@tcp(db.internal:3306)/?tls=skip-verify&timeout=3s"}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "app", cfg.User)
+		assert.Equal(t, "secret", cfg.Passwd)
+		assert.Equal(t, "db.internal:3306", cfg.Addr)
+		assert.Equal(t, dbTLSSkipVerify, cfg.TLSConfig)
+		assert.Equal(t, 3*time.Second, cfg.Timeout)
+
+		cfg, err = DBConfig{DSN: "app:secret@tcp(db.internal:3306)/", Password: "rotated", TLS: dbTLSVerify}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "rotated", cfg.Passwd, "settings override the DSN")
+		assert.Equal(t, "db.internal:3306", cfg.Addr, "the DSN address is kept without host and port")
+		assert.Equal(t, dbTLSVerify, cfg.TLSConfig)
+	})
+
+	t.Run("custom CA and client certificate", func(t *testing.T) {
+		certPath, keyPath := writeTestCertificate(t)
+		cfg, err := DBConfig{User: "app", TLS: dbTLSPreferred, TLSCA: certPath, TLSCert: certPath, TLSKey: keyPath}.mysqlConfig()
+		require.NoError(t, err)
+		require.NotNil(t, cfg.TLS)
+		assert.NotNil(t, cfg.TLS.RootCAs)
+		assert.Len(t, cfg.TLS.Certificates, 1)
+		assert.False(t, cfg.TLS.InsecureSkipVerify)
+		assert.True(t, cfg.AllowFallbackToPlaintext)
+
+		_, err = DBConfig{User: "app", TLSCA: keyPath}.mysqlConfig()
+		assert.ErrorContains(t, err, "no certificates found")
+	})
+
+	t.Run("server public key", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "public_key.pem")
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+		cfg, err := DBConfig{User: "app", ServerPublicKey: path}.mysqlConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "file:"+path, cfg.ServerPubKey)
+
+		_, err = DBConfig{User: "app", ServerPublicKey: "testdata/missing.pem"}.mysqlConfig()
+		assert.ErrorContains(t, err, "failed to read the server public key")
+	})
+}
+
+func TestDBConfig_Validate(t *testing.T) {
+	assert.NoError(t, DBConfig{DSN: "app:secret@tcp(db:3306)/", TLS: dbTLSPreferred}.Validate())
+
+	tests := []struct {
+		name     string
+		config   DBConfig
+		errorMsg string
+	}{
+		{name: "invalid dsn", config: DBConfig{DSN: "app:secret@db:3306"}, errorMsg: "invalid dsn"},
+		{name: "tls mode", config: DBConfig{TLS: "required"}, errorMsg: "tls must be one of false, true, skip-verify, preferred: required"},
+		{name: "cert without key", config: DBConfig{TLSCert: "cert.pem"}, errorMsg: "tlsCert and tlsKey must be set together"},
+		{name: "CA without TLS", config: DBConfig{TLS: dbTLSDisabled, TLSCA: "ca.pem"}, errorMsg: "can't be used when tls is false"},
+		{name: "two passwords", config: DBConfig{Password: "secret", PasswordFile: "password.txt"}, errorMsg: "only one of password, passwordFile and passwordCommand can be set"},
+		{name: "negative timeout", config: DBConfig{ReadTimeout: -time.Second}, errorMsg: "must not be negative"},
+		{name: "quoted time zone", config: DBConfig{TimeZone: "'; DROP"}, errorMsg: "invalid timeZone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.config.Validate(), tt.errorMsg)
+		})
+	}
+}
+
+func TestMaskDSN(t *testing.T) {
+	assert.Equal(t, "app:********@tcp(db.internal:3306)/?tls=true", maskDSN("app:secret@tcp(db.internal:3306)/?tls=true"))
+	assert.Equal(t, "app@tcp(db.internal:3306)/", maskDSN("app@tcp(db.internal:3306)/"))
+	assert.Equal(t, maskedSecret, maskDSN("invalid"))
+}
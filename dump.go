@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// formatDDL is the format of the dump subcommand that writes SHOW CREATE statements
+const formatDDL = "ddl"
+
+// runDump implements the dump subcommand, which writes a complete schema snapshot to a file:
+//
+//	mysql-schema-explorer-mcp dump --db ecshop --format json --out schema.json
+func runDump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	dbName := flags.String("db", "", "The name of the database to dump (default: the fixed database of the connection, e.g. DB_NAME)")
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "The config file (default: CONFIG_FILE)")
+	connection := flags.String("connection", "", "The connection to dump from when several connections are configured (default: the first one)")
+	format := flags.String("format", formatText, "The output format: text, json or ddl")
+	out := flags.String("out", "-", "The file to write the snapshot to, or - for standard output")
+	stripNoise := flags.Bool("strip-noise", true, "Remove AUTO_INCREMENT= and DEFINER= from the ddl format")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	if !slices.Contains([]string{formatText, formatJSON, formatDDL}, *format) {
+		return fmt.Errorf("--format must be %q, %q or %q", formatText, formatJSON, formatDDL)
+	}
+
+	config, err := readConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *dbName == "" {
+		if conn, ok := config.connection(*connection); ok {
+			*dbName = conn.Database
+		}
+	}
+	if *dbName == "" {
+		return fmt.Errorf("--db is required when the connection has no fixed database (DB_NAME)")
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	connections, closeDB, err := setupConnections(config)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	handler, err := connections.Handler(*connection)
+	if err != nil {
+		return err
+	}
+
+	output, err := handler.Dump(context.Background(), *dbName, *format, *stripNoise)
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+	return os.WriteFile(*out, output, 0o644)
+}
+
+// Dump renders the whole schema of the database in the given format (text, json or ddl)
+func (h *Handler) Dump(ctx context.Context, dbName string, format string, stripNoise bool) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		snapshot, err := h.FetchSnapshot(ctx, dbName)
+		if err != nil {
+			return nil, err
+		}
+		output, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(output, '\n'), nil
+	case formatDDL:
+		return h.dumpDDL(ctx, dbName, stripNoise)
+	default:
+		return h.dumpText(ctx, dbName)
+	}
+}
+
+// FetchSnapshot gets the complete schema of the database
+func (h *Handler) FetchSnapshot(ctx context.Context, dbName string) (SchemaSnapshot, error) {
+	tables, err := h.fetchTableSummaries(ctx, dbName, TableFilter{})
+	if err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("failed to get table information: %w", err)
+	}
+
+	snapshot := SchemaSnapshot{
+		Database:         dbName,
+		Tables:           []TableDetail{},
+		GeneratedColumns: map[string][]GeneratedColumn{},
+	}
+	for _, t := range tables {
+		detail, err := h.fetchTableDetail(ctx, dbName, t)
+		if err != nil {
+			return SchemaSnapshot{}, err
+		}
+		snapshot.Tables = append(snapshot.Tables, detail)
+
+		generatedColumns, err := h.db.FetchGeneratedColumns(ctx, dbName, t.Name)
+		if err != nil {
+			return SchemaSnapshot{}, fmt.Errorf("failed to get generated column information: %w", err)
+		}
+		if len(generatedColumns) > 0 {
+			snapshot.GeneratedColumns[t.Name] = generatedColumns
+		}
+	}
+
+	if snapshot.Views, err = h.db.FetchViews(ctx, dbName); err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("failed to get view information: %w", err)
+	}
+	if snapshot.Triggers, err = h.db.FetchTriggers(ctx, dbName); err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("failed to get trigger information: %w", err)
+	}
+	if snapshot.Routines, err = h.db.FetchRoutines(ctx, dbName); err != nil {
+		return SchemaSnapshot{}, fmt.Errorf("failed to get routine information: %w", err)
+	}
+	snapshot.Views, snapshot.Triggers, snapshot.Routines = emptyIfNil(snapshot.Views), emptyIfNil(snapshot.Triggers), emptyIfNil(snapshot.Routines)
+
+	return snapshot, nil
+}
+
+// dumpText renders the list_tables output followed by the describe_tables output of every table
+func (h *Handler) dumpText(ctx context.Context, dbName string) ([]byte, error) {
+	tables, err := h.fetchTableSummaries(ctx, dbName, TableFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table information: %w", err)
+	}
+
+	var output bytes.Buffer
+	err = h.templates.ListTables.Execute(&output, ListTablesData{
+		DBName:   dbName,
+		Detail:   detailKeys,
+		Total:    len(tables),
+		Returned: len(tables),
+		Tables:   tables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	for _, t := range tables {
+		detail, err := h.fetchTableDetail(ctx, dbName, t)
+		if err != nil {
+			return nil, err
+		}
+		output.WriteString("\n---\n\n")
+		if err := h.templates.DescribeTable.Execute(&output, detail); err != nil {
+			return nil, fmt.Errorf("failed to execute template: %w", err)
+		}
+	}
+
+	return output.Bytes(), nil
+}
+
+// dumpDDL renders the SHOW CREATE output of every table, view, routine and trigger.
+// Foreign key checks are disabled around the statements so that the tables can be created in name order
+func (h *Handler) dumpDDL(ctx context.Context, dbName string, stripNoise bool) ([]byte, error) {
+	tables, err := h.db.FetchTableWithComments(ctx, dbName, TableFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table information: %w", err)
+	}
+	views, err := h.db.FetchViews(ctx, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get view information: %w", err)
+	}
+	routines, err := h.db.FetchRoutines(ctx, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routine information: %w", err)
+	}
+	triggers, err := h.db.FetchTriggers(ctx, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trigger information: %w", err)
+	}
+
+	// INFORMATION_SCHEMA.TABLES also lists views, which are created after the tables they may select from
+	var objects []SchemaObject
+	for _, t := range tables {
+		if !slices.ContainsFunc(views, func(v SchemaObject) bool { return v.Name == t.Name }) {
+			objects = append(objects, SchemaObject{Name: t.Name, Type: "TABLE"})
+		}
+	}
+	objects = append(objects, views...)
+	objects = append(objects, routines...)
+	objects = append(objects, triggers...)
+
+	data := ShowCreateData{DBName: dbName, Objects: []CreateStatement{}, NotFound: []string{}}
+	for _, o := range objects {
+		statement, err := h.db.FetchCreateStatement(ctx, dbName, o.Type, o.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the definition of %s: %w", o.Name, err)
+		}
+		if stripNoise {
+			statement = StripDDLNoise(statement)
+		}
+		data.Objects = append(data.Objects, CreateStatement{Name: o.Name, Type: o.Type, Statement: statement})
+	}
+
+	var output bytes.Buffer
+	fmt.Fprintf(&output, "-- Schema of database %s\nSET FOREIGN_KEY_CHECKS=0;\n\n", quoteIdentifier(dbName))
+	if err := h.templates.ShowCreate.Execute(&output, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	output.WriteString("\nSET FOREIGN_KEY_CHECKS=1;\n")
+
+	return output.Bytes(), nil
+}
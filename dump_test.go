@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDump_InvalidArguments(t *testing.T) {
+	t.Setenv("DB_NAME", "")
+
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{name: "no database", args: []string{"--format", "json"}, errorMsg: "--db is required"},
+		{name: "unknown format", args: []string{"--db", "ecshop", "--format", "yaml"}, errorMsg: "--format must be"},
+		{name: "unexpected argument", args: []string{"--db", "ecshop", "schema.json"}, errorMsg: "unexpected arguments: schema.json"},
+		{name: "unknown flag", args: []string{"--database", "ecshop"}, errorMsg: "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDump(tt.args)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema_impact.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, "")
+
+	t.Run("text", func(t *testing.T) {
+		output, err := handler.Dump(t.Context(), testDBName, formatText, true)
+		require.NoError(t, err)
+
+		text := string(output)
+		assert.True(t, strings.HasPrefix(text, `Tables in database "`+testDBName+`" (Total: 3)`), text)
+		assert.Contains(t, text, "\n---\n\n# Table: orders - Order header\n")
+		assert.Contains(t, text, "\n---\n\n# Table: users - User information\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		output, err := handler.Dump(t.Context(), testDBName, formatJSON, true)
+		require.NoError(t, err)
+
+		var snapshot struct {
+			Database string `json:"database"`
+			Tables   []struct {
+				Name    string `json:"name"`
+				Columns []struct {
+					Name string `json:"name"`
+				} `json:"columns"`
+			} `json:"tables"`
+			GeneratedColumns map[string][]GeneratedColumn `json:"generatedColumns"`
+			Views            []SchemaObject               `json:"views"`
+			Triggers         []SchemaObject               `json:"triggers"`
+			Routines         []SchemaObject               `json:"routines"`
+		}
+		require.NoError(t, json.Unmarshal(output, &snapshot))
+
+		assert.Equal(t, testDBName, snapshot.Database)
+		require.Len(t, snapshot.Tables, 3)
+		assert.Equal(t, "orders", snapshot.Tables[1].Name)
+		assert.Len(t, snapshot.Tables[1].Columns, 5)
+		assert.Equal(t, []string{"price_with_tax"}, []string{snapshot.GeneratedColumns["orders"][0].Name})
+		assert.Equal(t, "order_prices", snapshot.Views[0].Name)
+		assert.Equal(t, "trg_orders_price", snapshot.Triggers[0].Name)
+		assert.Equal(t, "count_user_orders", snapshot.Routines[0].Name)
+	})
+
+	t.Run("ddl", func(t *testing.T) {
+		output, err := handler.Dump(t.Context(), testDBName, formatDDL, true)
+		require.NoError(t, err)
+
+		ddl := string(output)
+		assert.True(t, strings.HasPrefix(ddl, "-- Schema of database `"+testDBName+"`\nSET FOREIGN_KEY_CHECKS=0;\n\n-- TABLE orders\nCREATE TABLE `orders`"), ddl)
+		assert.Contains(t, ddl, "\n-- TABLE users\nCREATE TABLE `users`")
+		assert.Contains(t, ddl, "\n-- VIEW order_prices\nCREATE ")
+		assert.Contains(t, ddl, "\n-- FUNCTION count_user_orders\nDELIMITER ;;\n")
+		assert.Contains(t, ddl, "\n-- TRIGGER trg_orders_price\nDELIMITER ;;\n")
+		assert.NotContains(t, ddl, "DEFINER=")
+		assert.True(t, strings.HasSuffix(ddl, "\nSET FOREIGN_KEY_CHECKS=1;\n"), ddl)
+	})
+
+	t.Run("ddl round trip", func(t *testing.T) {
+		output, err := handler.Dump(t.Context(), testDBName, formatDDL, true)
+		require.NoError(t, err)
+		parsed, err := ParseDDL(string(output), testDBName)
+		require.NoError(t, err)
+
+		live, err := handler.FetchSnapshot(t.Context(), testDBName)
+		require.NoError(t, err)
+		assert.Equal(t, live.Routines, parsed[0].Routines, "routines parsed from the dump have the definitions of INFORMATION_SCHEMA")
+	})
+}
@@ -347,7 +347,7 @@ func TestE2E_ToolDefinitions(t *testing.T) {
 		json.Unmarshal(resp.Result, &result)
 		tools := result["tools"].([]interface{})
 
-		assert.Len(t, tools, 2)
+		assert.Len(t, tools, 6)
 
 		// Check list_tables has dbName parameter
 		listTables := findTool(tools, "list_tables")
@@ -360,6 +360,12 @@ func TestE2E_ToolDefinitions(t *testing.T) {
 		properties = getToolProperties(describeTables)
 		_, hasDBName = properties["dbName"]
 		assert.True(t, hasDBName, "describe_tables should have dbName in normal mode")
+
+		// Check table_dependency_order has dbName parameter
+		dependencyOrder := findTool(tools, "table_dependency_order")
+		properties = getToolProperties(dependencyOrder)
+		_, hasDBName = properties["dbName"]
+		assert.True(t, hasDBName, "table_dependency_order should have dbName in normal mode")
 	})
 
 	t.Run("fixed mode has no dbName parameter", func(t *testing.T) {
@@ -385,7 +391,8 @@ func TestE2E_ToolDefinitions(t *testing.T) {
 		json.Unmarshal(resp.Result, &result)
 		tools := result["tools"].([]interface{})
 
-		assert.Len(t, tools, 2)
+		assert.Len(t, tools, 5)
+		assert.Nil(t, findTool(tools, "diff_schemas"), "a fixed database has nothing to compare with without SNAPSHOT_DIR")
 
 		// Check list_tables has no dbName parameter
 		listTables := findTool(tools, "list_tables")
@@ -398,5 +405,11 @@ func TestE2E_ToolDefinitions(t *testing.T) {
 		properties = getToolProperties(describeTables)
 		_, hasDBName = properties["dbName"]
 		assert.False(t, hasDBName, "describe_tables should not have dbName in fixed mode")
+
+		// Check table_dependency_order has no dbName parameter
+		dependencyOrder := findTool(tools, "table_dependency_order")
+		properties = getToolProperties(dependencyOrder)
+		_, hasDBName = properties["dbName"]
+		assert.False(t, hasDBName, "table_dependency_order should not have dbName in fixed mode")
 	})
 }
@@ -3,31 +3,111 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"text/template"
+	"slices"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // Handler struct implements the MCP handler
 type Handler struct {
-	db          *DB
+	db          DB
 	fixedDBName string
+	inference   InferenceConfig
+	virtualFKs  []VirtualForeignKey
+	templates   *Templates
+	filter      ObjectFilter
+	policies    []AccessPolicy
+	access      *accessControl // Databases and tables visible to each caller, built from filter and policies
+	connection  string         // The name of the connection, which policies can be scoped to
+	snapshotDir string         // The directory of the snapshots that diff_schemas can compare with ("" disables targetSnapshot)
+
+	listTablesMaxBytes int // Default output limit of list_tables (0 means unlimited)
+}
+
+// HandlerOption configures optional behavior of Handler
+type HandlerOption func(*Handler)
+
+// WithInference enables heuristic inference of undeclared relationships
+func WithInference(config InferenceConfig) HandlerOption {
+	return func(h *Handler) {
+		h.inference = config
+	}
+}
+
+// WithVirtualForeignKeys adds user-declared relationships that the database doesn't enforce
+func WithVirtualForeignKeys(vfks []VirtualForeignKey) HandlerOption {
+	return func(h *Handler) {
+		h.virtualFKs = vfks
+	}
+}
+
+// WithTemplates replaces the built-in output templates
+func WithTemplates(templates *Templates) HandlerOption {
+	return func(h *Handler) {
+		h.templates = templates
+	}
+}
+
+// WithAccessPolicies restricts the databases and tables that each caller can see
+func WithAccessPolicies(policies []AccessPolicy) HandlerOption {
+	return func(h *Handler) {
+		h.policies = policies
+	}
 }
 
-func NewHandler(db *DB, fixedDBName string) *Handler {
-	return &Handler{db: db, fixedDBName: fixedDBName}
+// WithConnectionName sets the name of the connection that the handler serves, which policies can be scoped to
+func WithConnectionName(name string) HandlerOption {
+	return func(h *Handler) {
+		h.connection = name
+	}
+}
+
+// WithObjectFilter hides databases and tables from every caller
+func WithObjectFilter(filter ObjectFilter) HandlerOption {
+	return func(h *Handler) {
+		h.filter = filter
+	}
 }
 
-// getDatabaseName extracts the database name from the request or returns the fixed DB name
-func (h *Handler) getDatabaseName(request mcp.CallToolRequest) (string, error) {
+// WithSnapshotDir sets the directory of the snapshot files that diff_schemas can compare with
+func WithSnapshotDir(dir string) HandlerOption {
+	return func(h *Handler) {
+		h.snapshotDir = dir
+	}
+}
+
+// WithListTablesMaxBytes sets the default output limit of list_tables
+func WithListTablesMaxBytes(maxBytes int) HandlerOption {
+	return func(h *Handler) {
+		h.listTablesMaxBytes = maxBytes
+	}
+}
+
+func NewHandler(db DB, fixedDBName string, opts ...HandlerOption) *Handler {
+	h := &Handler{db: db, fixedDBName: fixedDBName, templates: builtinTemplates()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if !h.filter.IsZero() || h.policies != nil {
+		h.access = &accessControl{filter: h.filter, policies: h.policies, connection: h.connection}
+		h.db = &accessDB{DB: h.db, access: h.access}
+	}
+	return h
+}
+
+// getDatabaseName extracts the database name from the request or returns the fixed DB name,
+// provided that the caller may see the database
+func (h *Handler) getDatabaseName(ctx context.Context, request mcp.CallToolRequest) (string, error) {
 	// Use fixed DB name if set
 	if h.fixedDBName != "" {
-		return h.fixedDBName, nil
+		return h.fixedDBName, h.access.checkDatabase(ctx, h.fixedDBName)
 	}
 
 	// Otherwise get from request
-	dbNameRaw, ok := request.Params.Arguments["dbName"]
+	dbNameRaw, ok := request.GetArguments()["dbName"]
 	if !ok {
 		return "", fmt.Errorf("database name is not specified")
 	}
@@ -37,55 +117,310 @@ func (h *Handler) getDatabaseName(request mcp.CallToolRequest) (string, error) {
 		return "", fmt.Errorf("database name is not specified correctly")
 	}
 
-	return dbName, nil
+	return dbName, h.access.checkDatabase(ctx, dbName)
+}
+
+// Output formats supported by the tools
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// getFormat extracts the output format from the request. The default is text
+func getFormat(request mcp.CallToolRequest) (string, error) {
+	format := request.GetString("format", formatText)
+	if format != formatText && format != formatJSON {
+		return "", fmt.Errorf("format must be %q or %q", formatText, formatJSON)
+	}
+	return format, nil
+}
+
+// Detail levels of list_tables, from the cheapest to the most detailed
+const (
+	detailNames         = "names"
+	detailNamesComments = "names+comments"
+	detailKeys          = "keys"
+	detailFull          = "full"
+)
+
+// listDetails lists the detail levels of list_tables
+var listDetails = []string{detailNames, detailNamesComments, detailKeys, detailFull}
+
+// getDetail extracts the detail level of list_tables from the request. The default is keys
+func getDetail(request mcp.CallToolRequest) (string, error) {
+	detail := request.GetString("detail", detailKeys)
+	if !slices.Contains(listDetails, detail) {
+		return "", fmt.Errorf("detail must be one of %s", strings.Join(listDetails, ", "))
+	}
+	return detail, nil
+}
+
+// newJSONResult returns data as MCP structured content, with the JSON text for clients that don't support it
+func newJSONResult(data any) (*mcp.CallToolResult, error) {
+	text, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode JSON: %v", err)), nil
+	}
+	return mcp.NewToolResultStructured(data, string(text)), nil
+}
+
+// fetchTableSummaries gets summary information for the tables matching the filter, including virtual and inferred relationships
+func (h *Handler) fetchTableSummaries(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	tables, err := h.db.FetchTableSummaries(ctx, dbName, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ApplyVirtualForeignKeys(tables, dbName, h.virtualFKs)
+
+	if h.inference.Enabled {
+		// Relationships may reference tables outside the filter, so inference always looks at all tables
+		allTables := tables
+		if !filter.IsZero() {
+			allTables, err = h.db.FetchAllTableSummaries(ctx, dbName)
+			if err != nil {
+				return nil, err
+			}
+			ApplyVirtualForeignKeys(allTables, dbName, h.virtualFKs)
+		}
+		columns, err := h.db.FetchAllColumns(ctx, dbName)
+		if err != nil {
+			return nil, err
+		}
+		indexed, err := h.db.FetchIndexedColumns(ctx, dbName)
+		if err != nil {
+			return nil, err
+		}
+		ApplyInferredForeignKeys(tables, InferForeignKeys(allTables, columns, indexed, h.inference.MinConfidence))
+	}
+
+	// Virtual foreign keys may reference tables that the caller can't see
+	for i := range tables {
+		tables[i].FK = h.access.redactForeignKeys(ctx, dbName, tables[i].FK)
+	}
+
+	return tables, nil
+}
+
+// getTableFilter reads the table filter arguments of list_tables. A cursor restores the filter of the call
+// that returned it, and the filter arguments may be repeated but not changed
+func getTableFilter(request mcp.CallToolRequest) (TableFilter, error) {
+	filter := TableFilter{
+		Prefix:  request.GetString("prefix", ""),
+		Pattern: request.GetString("pattern", ""),
+	}
+
+	limit := request.GetInt("limit", 0)
+	if limit < 0 {
+		return TableFilter{}, fmt.Errorf("limit must not be negative: %d", limit)
+	}
+	filter.Limit = limit
+
+	if v, ok := request.GetArguments()["hasComment"]; ok && v != nil {
+		hasComment, ok := v.(bool)
+		if !ok {
+			return TableFilter{}, fmt.Errorf("hasComment must be a boolean")
+		}
+		filter.HasComment = &hasComment
+	}
+
+	if cursor := request.GetString("cursor", ""); cursor != "" {
+		cursorFilter, err := decodeCursor(cursor)
+		if err != nil {
+			return TableFilter{}, err
+		}
+		if (filter.Prefix != "" && filter.Prefix != cursorFilter.Prefix) ||
+			(filter.Pattern != "" && filter.Pattern != cursorFilter.Pattern) ||
+			(filter.HasComment != nil && (cursorFilter.HasComment == nil || *filter.HasComment != *cursorFilter.HasComment)) {
+			return TableFilter{}, fmt.Errorf("the cursor was returned for a different filter; call list_tables with only the cursor to continue")
+		}
+		if filter.Limit > 0 {
+			// The page size may change between pages
+			cursorFilter.Limit = filter.Limit
+		}
+		filter = cursorFilter
+	}
+
+	return filter, nil
 }
 
 // ListTables returns summary information for all tables
 func (h *Handler) ListTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	dbName, err := h.getDatabaseName(request)
+	dbName, err := h.getDatabaseName(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	format, err := getFormat(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	detail, err := getDetail(request)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	// Get table information
-	tables, err := h.db.FetchAllTableSummaries(ctx, dbName)
+	filter, err := getTableFilter(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	maxBytes := h.listTablesMaxBytes
+	if v := request.GetInt("maxBytes", 0); v > 0 {
+		maxBytes = v
+	}
+	if v := request.GetInt("maxTokens", 0); v > 0 && (maxBytes == 0 || v*bytesPerToken < maxBytes) {
+		maxBytes = v * bytesPerToken
+	}
+
+	// Get table information. One extra table is fetched to know whether more tables follow the limit
+	pageFilter := filter
+	if filter.Limit > 0 {
+		pageFilter.Limit = filter.Limit + 1
+	}
+	// The cheaper detail levels skip the key queries entirely
+	var tables []TableSummary
+	if detail == detailNames || detail == detailNamesComments {
+		tables, err = h.db.FetchTableWithComments(ctx, dbName, pageFilter)
+	} else {
+		tables, err = h.fetchTableSummaries(ctx, dbName, pageFilter)
+	}
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get table information: %v", err)), nil
 	}
+	hasMore := filter.Limit > 0 && len(tables) > filter.Limit
+	if hasMore {
+		tables = tables[:filter.Limit]
+	}
+
+	if detail == detailFull {
+		// Only the columns of the page are fetched, which matters on schemas with thousands of tables
+		names := make([]string, len(tables))
+		for i, table := range tables {
+			names[i] = table.Name
+		}
+		columns, err := h.db.FetchColumns(ctx, dbName, names)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get column information: %v", err)), nil
+		}
+		for i := range tables {
+			tables[i].Columns = columns[tables[i].Name]
+		}
+	}
+
+	// Count the total and the tables remaining after the page, which are the ones after the last table of the page
+	countFilter := filter
+	countFilter.After, countFilter.Limit = "", 0
+	if hasMore {
+		countFilter.After = tables[len(tables)-1].Name
+	}
+	total, remaining, err := h.db.CountTables(ctx, dbName, countFilter)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get table information: %v", err)), nil
+	}
+	if !hasMore {
+		remaining = 0
+	}
+
+	// Build the output of the first n tables, with the continuation for the rest
+	dataFor := func(n int) ListTablesData {
+		data := ListTablesData{
+			DBName:   dbName,
+			Detail:   detail,
+			Total:    total,
+			Returned: n,
+			Tables:   emptyIfNil(tables[:n]),
+		}
+		if n < len(tables) || hasMore {
+			data.Omitted = len(tables) - n + remaining
+			next := filter
+			next.After = tables[n-1].Name
+			data.NextCursor = encodeCursor(next)
+		}
+		return data
+	}
+
+	if format == formatJSON {
+		var fitted ListTablesData
+		text, err := fitTables(len(tables), maxBytes, func(n int) (string, error) {
+			fitted = dataFor(n)
+			b, err := json.MarshalIndent(fitted, "", "  ")
+			return string(b), err
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to encode JSON: %v", err)), nil
+		}
+		return mcp.NewToolResultStructured(fitted, text), nil
+	}
 
 	// No tables found
 	if len(tables) == 0 {
+		switch {
+		case filter.After != "":
+			return mcp.NewToolResultText("No more tables exist after the cursor."), nil
+		case !filter.IsZero():
+			return mcp.NewToolResultText("No tables match the filter."), nil
+		}
 		return mcp.NewToolResultText("No tables exist in the database."), nil
 	}
 
 	// Create output
-	var output bytes.Buffer
-	{
-		tmpl, err := template.New("listTables").Funcs(funcMap).Parse(listTablesTemplate)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse template: %v", err)), nil
-		}
+	output, err := fitTables(len(tables), maxBytes, func(n int) (string, error) {
+		var output bytes.Buffer
+		err := h.templates.ListTables.Execute(&output, dataFor(n))
+		return output.String(), err
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+	}
 
-		if err := tmpl.Execute(&output, ListTablesData{
-			DBName: dbName,
-			Tables: tables,
-		}); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
-		}
+	return mcp.NewToolResultText(output), nil
+}
+
+// fetchTableDetail gets the detail information of a table. The foreign keys are taken from the summary
+// so that virtual and inferred relationships are included
+func (h *Handler) fetchTableDetail(ctx context.Context, dbName string, table TableSummary) (TableDetail, error) {
+	primaryKeys, err := h.db.FetchPrimaryKeys(ctx, dbName, table.Name)
+	if err != nil {
+		return TableDetail{}, fmt.Errorf("failed to get primary key information: %w", err)
 	}
 
-	return mcp.NewToolResultText(output.String()), nil
+	uniqueKeys, err := h.db.FetchUniqueKeys(ctx, dbName, table.Name)
+	if err != nil {
+		return TableDetail{}, fmt.Errorf("failed to get unique key information: %w", err)
+	}
+
+	columns, err := h.db.FetchTableColumns(ctx, dbName, table.Name)
+	if err != nil {
+		return TableDetail{}, fmt.Errorf("failed to get column information: %w", err)
+	}
+
+	indexes, err := h.db.FetchTableIndexes(ctx, dbName, table.Name)
+	if err != nil {
+		return TableDetail{}, fmt.Errorf("failed to get index information: %w", err)
+	}
+
+	return TableDetail{
+		Name:        table.Name,
+		Comment:     table.Comment,
+		Columns:     columns,
+		PrimaryKeys: primaryKeys,
+		UniqueKeys:  uniqueKeys,
+		ForeignKeys: table.FK,
+		Indexes:     indexes,
+	}, nil
 }
 
 // DescribeTables is a handler method that returns detailed information for the specified tables
 func (h *Handler) DescribeTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	dbName, err := h.getDatabaseName(request)
+	dbName, err := h.getDatabaseName(ctx, request)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	// Create list of table names
-	tableNamesRaw, ok := request.Params.Arguments["tableNames"]
+	tableNamesRaw, ok := request.GetArguments()["tableNames"]
 	if !ok {
 		return mcp.NewToolResultError("Table names are not specified"), nil
 	}
@@ -103,16 +438,23 @@ func (h *Handler) DescribeTables(ctx context.Context, request mcp.CallToolReques
 		return mcp.NewToolResultError("No valid table names are specified"), nil
 	}
 
-	allTables, err := h.db.FetchAllTableSummaries(ctx, dbName)
+	format, err := getFormat(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	allTables, err := h.fetchTableSummaries(ctx, dbName, TableFilter{})
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get table information: %v", err)), nil
 	}
 
 	// Prepare output
 	var output bytes.Buffer
-	tmpl, err := template.New("describeTableDetail").Funcs(funcMap).Parse(describeTableDetailTemplate)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse template: %v", err)), nil
+
+	jsonData := DescribeTablesData{
+		DBName:   dbName,
+		Tables:   []TableDetail{},
+		NotFound: []string{},
 	}
 
 	// Get information for all tables
@@ -134,52 +476,300 @@ func (h *Handler) DescribeTables(ctx context.Context, request mcp.CallToolReques
 		}
 
 		if !tableFound {
+			jsonData.NotFound = append(jsonData.NotFound, tableName)
 			output.WriteString(fmt.Sprintf("# Table: %s\nTable not found\n", tableName))
 			continue
 		}
 
 		// Get table detail information
-		primaryKeys, err := h.db.FetchPrimaryKeys(ctx, dbName, tableName)
+		tableDetail, err := h.fetchTableDetail(ctx, dbName, tableInfo)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to get primary key information: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to describe table %s: %v", tableName, err)), nil
 		}
 
-		uniqueKeys, err := h.db.FetchUniqueKeys(ctx, dbName, tableName)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to get unique key information: %v", err)), nil
+		if format == formatJSON {
+			jsonData.Tables = append(jsonData.Tables, tableDetail)
+			continue
 		}
 
-		foreignKeys, err := h.db.FetchForeignKeys(ctx, dbName, tableName)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to get foreign key information: %v", err)), nil
+		// Execute the template and write to the buffer
+		if err := h.templates.DescribeTable.Execute(&output, tableDetail); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
 		}
+	}
 
-		columns, err := h.db.FetchTableColumns(ctx, dbName, tableName)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to get column information: %v", err)), nil
+	if format == formatJSON {
+		return newJSONResult(jsonData)
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// TableDependencyOrder returns tables in FK-safe insertion and deletion order, with cycles and self-references
+func (h *Handler) TableDependencyOrder(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dbName, err := h.getDatabaseName(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	format, err := getFormat(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tables, err := h.fetchTableSummaries(ctx, dbName, TableFilter{})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get table information: %v", err)), nil
+	}
+
+	data := DependencyOrderData{
+		DBName:          dbName,
+		DependencyOrder: BuildDependencyOrder(tables),
+	}
+	if format == formatJSON {
+		return newJSONResult(data)
+	}
+
+	if len(tables) == 0 {
+		return mcp.NewToolResultText("No tables exist in the database."), nil
+	}
+
+	var output bytes.Buffer
+	if err := h.templates.DependencyOrder.Execute(&output, data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// DiffSchemas reports the tables, columns, keys and indexes that differ between two databases, or a database and a snapshot
+func (h *Handler) DiffSchemas(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sourceDBName := request.GetString("sourceDbName", h.fixedDBName)
+	if sourceDBName == "" {
+		return mcp.NewToolResultError("sourceDbName is not specified"), nil
+	}
+	// A fixed database is the only one that can be read, so only a snapshot can differ from it
+	if h.fixedDBName != "" {
+		for _, arg := range []string{"sourceDbName", "targetDbName"} {
+			if name := request.GetString(arg, ""); name != "" && name != h.fixedDBName {
+				return mcp.NewToolResultError(fmt.Sprintf("%s must be %s, the fixed database; compare it with targetSnapshot instead", arg, h.fixedDBName)), nil
+			}
 		}
+	}
+	targetDBName := request.GetString("targetDbName", h.fixedDBName)
+	targetSnapshot := request.GetString("targetSnapshot", "")
+	if targetDBName == "" && targetSnapshot == "" {
+		return mcp.NewToolResultError("targetDbName or targetSnapshot must be specified"), nil
+	}
+	if request.GetString("targetDbName", "") != "" && targetSnapshot != "" {
+		return mcp.NewToolResultError("targetDbName and targetSnapshot cannot be specified together"), nil
+	}
+	// Comparing a database with itself always reports no differences
+	if targetSnapshot == "" && sourceDBName == targetDBName {
+		return mcp.NewToolResultError(fmt.Sprintf("sourceDbName and targetDbName are both %s; compare it with another database or targetSnapshot", sourceDBName)), nil
+	}
+
+	format := request.GetString("format", formatText)
+	if format != formatText && format != formatJSON && format != formatDDL {
+		return mcp.NewToolResultError(fmt.Sprintf("format must be %q, %q or %q", formatText, formatJSON, formatDDL)), nil
+	}
 
-		indexes, err := h.db.FetchTableIndexes(ctx, dbName, tableName)
+	var diff SchemaDiff
+	var err error
+	if targetSnapshot != "" {
+		var path string
+		path, err = h.snapshotPath(targetSnapshot)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to get index information: %v", err)), nil
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		diff, err = h.DiffSnapshotFile(ctx, sourceDBName, path)
+		diff.TargetFile = targetSnapshot
+	} else {
+		diff, err = h.DiffDatabases(ctx, sourceDBName, targetDBName)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	switch format {
+	case formatJSON:
+		return newJSONResult(diff)
+	case formatDDL:
+		return mcp.NewToolResultText(formatMigration(diff, BuildMigration(diff))), nil
+	}
+
+	var output bytes.Buffer
+	if err := h.templates.SchemaDiff.Execute(&output, diff); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// AnalyzeColumnImpact reports every index, key, foreign key, generated column, view, trigger and routine that references a column
+func (h *Handler) AnalyzeColumnImpact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dbName, err := h.getDatabaseName(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	format, err := getFormat(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	columnRaw, ok := request.GetArguments()["column"]
+	if !ok {
+		return mcp.NewToolResultError("Column is not specified"), nil
+	}
+	columnRef, _ := columnRaw.(string)
+	tableName, columnName, ok := strings.Cut(columnRef, ".")
+	if !ok || tableName == "" || columnName == "" {
+		return mcp.NewToolResultError("Column must be specified as table.column"), nil
+	}
+
+	allTables, err := h.fetchTableSummaries(ctx, dbName, TableFilter{})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get table information: %v", err)), nil
+	}
+	tableIdx := slices.IndexFunc(allTables, func(t TableSummary) bool { return t.Name == tableName })
+	if tableIdx < 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Table not found: %s", tableName)), nil
+	}
+
+	columns, err := h.db.FetchTableColumns(ctx, dbName, tableName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get column information: %v", err)), nil
+	}
+	if !slices.ContainsFunc(columns, func(c ColumnInfo) bool { return strings.EqualFold(c.Name, columnName) }) {
+		return mcp.NewToolResultError(fmt.Sprintf("Column not found: %s.%s", tableName, columnName)), nil
+	}
+
+	impact := ColumnImpact{
+		Table:       tableName,
+		Column:      columnName,
+		OutgoingFKs: OutgoingForeignKeys(allTables[tableIdx], columnName),
+	}
+
+	impact.Indexes, err = h.db.FetchColumnIndexes(ctx, dbName, tableName, columnName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get index information: %v", err)), nil
+	}
+
+	// Declared foreign keys may come from any database, the others only exist in the table summaries
+	declaredFKs, err := h.db.FetchReferencingForeignKeys(ctx, dbName, tableName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get foreign key information: %v", err)), nil
+	}
+	for _, e := range declaredFKs {
+		if containsFold(e.FK.RefColumns, columnName) {
+			impact.IncomingFKs = append(impact.IncomingFKs, e)
 		}
+	}
+	for _, e := range IncomingForeignKeys(allTables, tableName, columnName) {
+		if e.FK.Origin != FKOriginDeclared {
+			impact.IncomingFKs = append(impact.IncomingFKs, e)
+		}
+	}
+
+	generatedColumns, err := h.db.FetchGeneratedColumns(ctx, dbName, tableName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get generated column information: %v", err)), nil
+	}
+	impact.GeneratedColumns = GeneratedColumnsReferencing(generatedColumns, columnName)
+
+	views, err := h.db.FetchViews(ctx, dbName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get view information: %v", err)), nil
+	}
+	impact.Views = ObjectsReferencing(views, tableName, columnName)
+
+	triggers, err := h.db.FetchTriggers(ctx, dbName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get trigger information: %v", err)), nil
+	}
+	impact.Triggers = ObjectsReferencing(triggers, tableName, columnName)
+
+	routines, err := h.db.FetchRoutines(ctx, dbName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get routine information: %v", err)), nil
+	}
+	impact.Routines = ObjectsReferencing(routines, tableName, columnName)
 
-		// Create data to pass to the template
-		tableDetail := TableDetail{
-			Name:        tableName,
-			Comment:     tableInfo.Comment,
-			Columns:     columns,
-			PrimaryKeys: primaryKeys,
-			UniqueKeys:  uniqueKeys,
-			ForeignKeys: foreignKeys,
-			Indexes:     indexes,
+	if format == formatJSON {
+		return newJSONResult(impact)
+	}
+
+	var output bytes.Buffer
+	if err := h.templates.ColumnImpact.Execute(&output, impact); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(output.String()), nil
+}
+
+// ShowCreate returns the SHOW CREATE output of the specified tables, views, routines and triggers
+func (h *Handler) ShowCreate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dbName, err := h.getDatabaseName(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	format, err := getFormat(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	objectNames := request.GetStringSlice("objectNames", nil)
+	objectNames = slices.DeleteFunc(objectNames, func(name string) bool { return name == "" })
+	if len(objectNames) == 0 {
+		return mcp.NewToolResultError("No valid object names are specified"), nil
+	}
+
+	objectType := request.GetString("objectType", "")
+	if objectType != "" && !slices.Contains(objectTypes, objectType) {
+		return mcp.NewToolResultError(fmt.Sprintf("objectType must be one of %s", strings.Join(objectTypes, ", "))), nil
+	}
+	stripNoise := request.GetBool("stripNoise", false)
+
+	data := ShowCreateData{
+		DBName:   dbName,
+		Objects:  []CreateStatement{},
+		NotFound: []string{},
+	}
+	for _, name := range objectNames {
+		// Without a type, every object with the name is returned, e.g. both a procedure and a function
+		types, err := h.db.FetchObjectTypes(ctx, dbName, name)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get object information: %v", err)), nil
+		}
+		if objectType != "" {
+			types = slices.DeleteFunc(types, func(t string) bool { return t != strings.ToUpper(objectType) })
+		}
+		if len(types) == 0 {
+			data.NotFound = append(data.NotFound, name)
+			continue
 		}
 
-		// Execute the template and write to the buffer
-		if err := tmpl.Execute(&output, tableDetail); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+		for _, t := range types {
+			statement, err := h.db.FetchCreateStatement(ctx, dbName, t, name)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to get the definition of %s: %v", name, err)), nil
+			}
+			if stripNoise {
+				statement = StripDDLNoise(statement)
+			}
+			data.Objects = append(data.Objects, CreateStatement{Name: name, Type: t, Statement: statement})
 		}
 	}
 
+	if format == formatJSON {
+		return newJSONResult(data)
+	}
+
+	var output bytes.Buffer
+	if err := h.templates.ShowCreate.Execute(&output, data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute template: %v", err)), nil
+	}
+
 	return mcp.NewToolResultText(output.String()), nil
 }
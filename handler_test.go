@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -16,13 +17,7 @@ func TestListTables(t *testing.T) {
 
 	ctx := t.Context()
 	req := mcp.CallToolRequest{
-		Params: struct {
-			Name      string                 `json:"name"`
-			Arguments map[string]interface{} `json:"arguments,omitempty"`
-			Meta      *struct {
-				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
-			} `json:"_meta,omitempty"`
-		}{
+		Params: mcp.CallToolParams{
 			// Name: "ListTables", // Set tool name if necessary
 			Arguments: map[string]interface{}{
 				"dbName": testDBName,
@@ -60,13 +55,7 @@ func TestDescribeTables(t *testing.T) {
 
 	ctx := t.Context()
 	req := mcp.CallToolRequest{
-		Params: struct {
-			Name      string                 `json:"name"`
-			Arguments map[string]interface{} `json:"arguments,omitempty"`
-			Meta      *struct {
-				ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
-			} `json:"_meta,omitempty"`
-		}{
+		Params: mcp.CallToolParams{
 			// Name: "DescribeTables", // Set tool name if necessary
 			Arguments: map[string]interface{}{
 				"dbName":     testDBName,
@@ -134,3 +123,485 @@ func TestDescribeTables(t *testing.T) {
 
 	assert.Equal(t, expectedOutput, textContent, "Output content should match the expected format")
 }
+
+func TestListTables_InferForeignKeys(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema_without_fk.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName, WithInference(InferenceConfig{Enabled: true, MinConfidence: 0.6}))
+
+	result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	expectedOutput := `Tables in database "` + testDBName + `" (Total: 4)
+Format: Table Name - Table Comment [PK: Primary Key] [UK: Unique Key 1; Unique Key 2...] [FK: Foreign Key -> Referenced Table.Column; ...]
+* Composite keys (keys composed of multiple columns) are grouped in parentheses: (col1, col2)
+* Multiple different key constraints are separated by semicolons: key1; key2
+
+- categories - Category master [PK: category_code]
+- orders - Order header [PK: id] [FK: user_id -> users.id (inferred 1.00); shop_code -> shops.code (inferred 0.75); category_code -> categories.category_code (inferred 0.80)]
+- shops - Shop master [PK: id] [UK: code]
+- users - User information [PK: id]
+`
+	textContent := result.Content[0].(mcp.TextContent).Text
+	assert.Equal(t, expectedOutput, textContent)
+}
+
+func TestDescribeTables_VirtualForeignKeys(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema_without_fk.sql")
+
+	vfks, err := LoadVirtualForeignKeys("testdata/virtual_foreign_keys.yaml")
+	require.NoError(t, err)
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, "", WithVirtualForeignKeys(vfks))
+
+	result, err := handler.DescribeTables(t.Context(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"dbName":     testDBName,
+				"tableNames": []interface{}{"orders"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	expectedOutput := `# Table: orders - Order header
+
+## Columns
+- id: int NOT NULL [Order ID]
+- user_id: int NOT NULL [User ID]
+- shop_code: varchar(50) NOT NULL [Shop code]
+- category_code: varchar(50) NOT NULL [Category code]
+- note_id: varchar(50) NULL [Not a reference]
+
+## Key Information
+[PK: id]
+[FK: user_id -> users.id (virtual); (shop_code, category_code) -> crm.shop_categories.(shop_code, category_code) (virtual)]
+[INDEX: user_id]
+`
+	textContent := result.Content[0].(mcp.TextContent).Text
+	assert.Equal(t, expectedOutput, textContent)
+}
+
+func TestTableDependencyOrder(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	result, err := handler.TableDependencyOrder(t.Context(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	expectedOutput := `Table dependency order in database "` + testDBName + `" (Total: 4)
+* Tables marked [cycle N] are part of a foreign key cycle and cannot be fully ordered; see Cycles
+
+## Insertion Order (parents before children)
+1. products
+2. users
+3. orders
+4. order_items
+
+## Deletion Order (children before parents)
+1. order_items
+2. orders
+3. users
+4. products
+`
+	textContent := result.Content[0].(mcp.TextContent).Text
+	assert.Equal(t, expectedOutput, textContent)
+}
+
+func TestAnalyzeColumnImpact(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema_impact.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	analyze := func(t *testing.T, column string) string {
+		result, err := handler.AnalyzeColumnImpact(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"column": column,
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	t.Run("keys and foreign keys", func(t *testing.T) {
+		text := analyze(t, "users.id")
+
+		assert.Contains(t, text, "# Column Impact: users.id")
+		assert.Contains(t, text, "## Indexes and Keys\n- PRIMARY KEY PRIMARY: id\n")
+		assert.Contains(t, text, "## Outgoing Foreign Keys\n- None\n")
+		assert.Contains(t, text, "## Incoming Foreign Keys\n- orders: user_id -> users.id [fk_user]\n")
+	})
+
+	t.Run("generated columns, views, triggers and routines", func(t *testing.T) {
+		text := analyze(t, "orders.price")
+
+		assert.Contains(t, text, "## Indexes and Keys\n- None\n")
+		assert.Contains(t, text, "## Generated Columns\n- price_with_tax: ")
+		assert.Contains(t, text, "## Views\n- order_prices\n")
+		assert.Contains(t, text, "## Triggers\n- trg_orders_price (BEFORE INSERT ON orders)\n")
+		assert.Contains(t, text, "## Routines\n- None\n")
+	})
+
+	t.Run("composite index and routine", func(t *testing.T) {
+		text := analyze(t, "orders.user_id")
+
+		assert.Contains(t, text, "## Indexes and Keys\n- INDEX idx_user_date: (user_id, order_date)\n")
+		assert.Contains(t, text, "## Outgoing Foreign Keys\n- orders: user_id -> users.id [fk_user]\n")
+		assert.Contains(t, text, "## Routines\n- FUNCTION count_user_orders\n")
+	})
+}
+
+func TestListTables_JSONFormat(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"format": "json",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.False(t, result.IsError)
+
+	expectedJSON := `{
+		"database": "` + testDBName + `",
+		"detail": "keys",
+		"total": 4,
+		"returned": 4,
+		"tables": [
+			{"name": "order_items", "comment": "Order details", "primaryKey": ["order_id", "item_seq"],
+			 "uniqueKeys": [{"name": "uk_order_product", "columns": ["order_id", "product_maker", "product_internal_code"]}],
+			 "foreignKeys": [
+				{"name": "order_items_ibfk_1", "columns": ["order_id"], "refTable": "orders", "refColumns": ["id"]},
+				{"name": "order_items_ibfk_2", "columns": ["product_maker", "product_internal_code"], "refTable": "products", "refColumns": ["maker_code", "internal_code"]}
+			 ]},
+			{"name": "orders", "comment": "Order header", "primaryKey": ["id"], "uniqueKeys": [],
+			 "foreignKeys": [{"name": "orders_ibfk_1", "columns": ["user_id"], "refTable": "users", "refColumns": ["id"]}]},
+			{"name": "products", "comment": "Product master", "primaryKey": ["product_code"],
+			 "uniqueKeys": [{"name": "uk_maker_internal", "columns": ["maker_code", "internal_code"]}], "foreignKeys": []},
+			{"name": "users", "comment": "User information", "primaryKey": ["id"],
+			 "uniqueKeys": [{"name": "email", "columns": ["email"]}, {"name": "uk_tenant_employee", "columns": ["tenant_id", "employee_id"]}, {"name": "username", "columns": ["username"]}],
+			 "foreignKeys": []}
+		]
+	}`
+	textContent := result.Content[0].(mcp.TextContent).Text
+	assert.JSONEq(t, expectedJSON, textContent)
+	assert.NotNil(t, result.StructuredContent, "JSON format should return structured content")
+}
+
+func TestDescribeTables_JSONFormat(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	result, err := handler.DescribeTables(t.Context(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"tableNames": []interface{}{"orders", "missing"},
+				"format":     "json",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.False(t, result.IsError)
+
+	expectedJSON := `{
+		"database": "` + testDBName + `",
+		"tables": [
+			{"name": "orders", "comment": "Order header",
+			 "columns": [
+				{"name": "id", "type": "int", "nullable": false, "default": null, "comment": "Order ID"},
+				{"name": "user_id", "type": "int", "nullable": false, "default": null, "comment": "User ID (FK)"},
+				{"name": "order_date", "type": "datetime", "nullable": true, "default": null, "comment": "Order date"}
+			 ],
+			 "primaryKey": ["id"],
+			 "uniqueKeys": [],
+			 "foreignKeys": [{"name": "orders_ibfk_1", "columns": ["user_id"], "refTable": "users", "refColumns": ["id"]}],
+			 "indexes": [{"name": "fk_user", "columns": ["user_id"], "unique": false}, {"name": "id", "columns": ["id"], "unique": false}]}
+		],
+		"notFound": ["missing"]
+	}`
+	textContent := result.Content[0].(mcp.TextContent).Text
+	assert.JSONEq(t, expectedJSON, textContent)
+}
+
+func TestListTables_MaxBytes(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	listTables := func(t *testing.T, arguments map[string]interface{}) string {
+		result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: arguments},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	header := `Format: Table Name - Table Comment [PK: Primary Key] [UK: Unique Key 1; Unique Key 2...] [FK: Foreign Key -> Referenced Table.Column; ...]
+* Composite keys (keys composed of multiple columns) are grouped in parentheses: (col1, col2)
+* Multiple different key constraints are separated by semicolons: key1; key2
+`
+
+	// The first call is truncated after 2 tables
+	text := listTables(t, map[string]interface{}{"maxBytes": float64(800)})
+	cursor := encodeCursor(TableFilter{After: "orders"})
+	assert.Equal(t, `Tables in database "`+testDBName+`" (Total: 4, Returned: 2)
+`+header+`
+- order_items - Order details [PK: (order_id, item_seq)] [UK: (order_id, product_maker, product_internal_code)] [FK: order_id -> orders.id; (product_maker, product_internal_code) -> products.(maker_code, internal_code)]
+- orders - Order header [PK: id] [FK: user_id -> users.id]
+
+2 more tables were omitted. Call list_tables with cursor "`+cursor+`" to get them.
+`, text)
+
+	// The continuation returns the rest
+	text = listTables(t, map[string]interface{}{"maxBytes": float64(800), "cursor": cursor})
+	assert.Equal(t, `Tables in database "`+testDBName+`" (Total: 4, Returned: 2)
+`+header+`
+- products - Product master [PK: product_code] [UK: (maker_code, internal_code)]
+- users - User information [PK: id] [UK: email; (tenant_id, employee_id); username]
+`, text)
+}
+
+func TestListTables_LimitAndFilter(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	listTables := func(t *testing.T, arguments map[string]interface{}) ListTablesData {
+		arguments["format"] = "json"
+		result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: arguments},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		data, ok := result.StructuredContent.(ListTablesData)
+		require.True(t, ok)
+		return data
+	}
+	names := func(data ListTablesData) []string {
+		var names []string
+		for _, table := range data.Tables {
+			names = append(names, table.Name)
+		}
+		return names
+	}
+
+	t.Run("limit pages through the tables with a cursor", func(t *testing.T) {
+		data := listTables(t, map[string]interface{}{"limit": float64(3)})
+		assert.Equal(t, []string{"order_items", "orders", "products"}, names(data))
+		assert.Equal(t, 4, data.Total)
+		assert.Equal(t, 3, data.Returned)
+		assert.Equal(t, 1, data.Omitted)
+		assert.Equal(t, encodeCursor(TableFilter{After: "products", Limit: 3}), data.NextCursor)
+
+		data = listTables(t, map[string]interface{}{"limit": float64(3), "cursor": data.NextCursor})
+		assert.Equal(t, []string{"users"}, names(data))
+		assert.Equal(t, 4, data.Total)
+		assert.Equal(t, 1, data.Returned)
+		assert.Empty(t, data.NextCursor)
+	})
+
+	t.Run("the cursor keeps the filter", func(t *testing.T) {
+		data := listTables(t, map[string]interface{}{"prefix": "order", "limit": float64(1)})
+		assert.Equal(t, []string{"order_items"}, names(data))
+		assert.Equal(t, 1, data.Omitted)
+
+		data = listTables(t, map[string]interface{}{"cursor": data.NextCursor})
+		assert.Equal(t, []string{"orders"}, names(data), "tables outside the prefix are not returned")
+		assert.Equal(t, 2, data.Total)
+		assert.Empty(t, data.NextCursor)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		data := listTables(t, map[string]interface{}{"prefix": "order"})
+		assert.Equal(t, []string{"order_items", "orders"}, names(data))
+		assert.Equal(t, 2, data.Total)
+	})
+
+	t.Run("prefix does not treat _ as a wildcard", func(t *testing.T) {
+		data := listTables(t, map[string]interface{}{"prefix": "order_"})
+		assert.Equal(t, []string{"order_items"}, names(data))
+	})
+
+	t.Run("pattern", func(t *testing.T) {
+		data := listTables(t, map[string]interface{}{"pattern": "%s"})
+		assert.Equal(t, []string{"order_items", "orders", "products", "users"}, names(data))
+
+		data = listTables(t, map[string]interface{}{"pattern": "%er%"})
+		assert.Equal(t, []string{"order_items", "orders", "users"}, names(data))
+	})
+
+	t.Run("hasComment", func(t *testing.T) {
+		data := listTables(t, map[string]interface{}{"hasComment": true})
+		assert.Equal(t, 4, data.Total)
+
+		data = listTables(t, map[string]interface{}{"hasComment": false})
+		assert.Equal(t, 0, data.Total)
+		assert.Empty(t, data.Tables)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{"limit": float64(-1)}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestListTables_Detail(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema.sql")
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	listTables := func(t *testing.T, arguments map[string]interface{}) *mcp.CallToolResult {
+		result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: arguments},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		return result
+	}
+
+	t.Run("names", func(t *testing.T) {
+		result := listTables(t, map[string]interface{}{"detail": "names"})
+		require.False(t, result.IsError)
+		assert.Equal(t, `Tables in database "`+testDBName+`" (Total: 4)
+- order_items
+- orders
+- products
+- users
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("names+comments", func(t *testing.T) {
+		result := listTables(t, map[string]interface{}{"detail": "names+comments"})
+		require.False(t, result.IsError)
+		assert.Equal(t, `Tables in database "`+testDBName+`" (Total: 4)
+Format: Table Name - Table Comment
+
+- order_items - Order details
+- orders - Order header
+- products - Product master
+- users - User information
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("names in JSON only has names", func(t *testing.T) {
+		result := listTables(t, map[string]interface{}{"detail": "names", "prefix": "user", "format": "json"})
+		require.False(t, result.IsError)
+		assert.JSONEq(t, `{
+			"database": "`+testDBName+`",
+			"detail": "names",
+			"total": 1,
+			"returned": 1,
+			"tables": [{"name": "users"}]
+		}`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("full", func(t *testing.T) {
+		result := listTables(t, map[string]interface{}{"detail": "full", "prefix": "orders"})
+		require.False(t, result.IsError)
+		assert.Equal(t, `Tables in database "`+testDBName+`" (Total: 1)
+Format: Table Name - Table Comment [PK: Primary Key] [UK: Unique Key 1; Unique Key 2...] [FK: Foreign Key -> Referenced Table.Column; ...]
+* Composite keys (keys composed of multiple columns) are grouped in parentheses: (col1, col2)
+* Multiple different key constraints are separated by semicolons: key1; key2
+* Columns are listed under each table: - Column Name: Type NULL/NOT NULL [DEFAULT value] [Comment]
+
+- orders - Order header [PK: id] [FK: user_id -> users.id]
+  - id: int NOT NULL [Order ID]
+  - user_id: int NOT NULL [User ID (FK)]
+  - order_date: datetime NULL [Order date]
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("invalid detail", func(t *testing.T) {
+		result := listTables(t, map[string]interface{}{"detail": "everything"})
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestShowCreate(t *testing.T) {
+	dbConn := setupTestDB(t, "testdata/schema_impact.sql")
+	_, err := dbConn.Exec("INSERT INTO users (email) VALUES ('a@example.com')")
+	require.NoError(t, err)
+
+	db := NewDB(dbConn)
+	handler := NewHandler(db, testDBName)
+
+	showCreate := func(t *testing.T, arguments map[string]interface{}) string {
+		result, err := handler.ShowCreate(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: arguments},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	t.Run("table", func(t *testing.T) {
+		text := showCreate(t, map[string]interface{}{"objectNames": []interface{}{"users"}})
+		assert.Contains(t, text, "-- TABLE users\nCREATE TABLE `users` (\n")
+		assert.Contains(t, text, "UNIQUE KEY `uk_email` (`email`)")
+		assert.Contains(t, text, "AUTO_INCREMENT=2")
+	})
+
+	t.Run("stripNoise removes AUTO_INCREMENT and DEFINER", func(t *testing.T) {
+		text := showCreate(t, map[string]interface{}{
+			"objectNames": []interface{}{"users", "order_prices", "trg_orders_price", "count_user_orders"},
+			"stripNoise":  true,
+		})
+		assert.Contains(t, text, "-- TABLE users\n")
+		assert.Contains(t, text, "-- VIEW order_prices\n")
+		assert.Contains(t, text, "-- TRIGGER trg_orders_price\nDELIMITER ;;\nCREATE TRIGGER")
+		assert.Contains(t, text, "-- FUNCTION count_user_orders\nDELIMITER ;;\nCREATE FUNCTION")
+		assert.NotContains(t, text, "AUTO_INCREMENT=")
+		assert.NotContains(t, text, "DEFINER=")
+	})
+
+	t.Run("objectType and not found objects", func(t *testing.T) {
+		text := showCreate(t, map[string]interface{}{
+			"objectNames": []interface{}{"users", "order_prices", "missing"},
+			"objectType":  "view",
+		})
+		assert.True(t, strings.HasPrefix(text, "-- VIEW order_prices\n"), text)
+		assert.True(t, strings.HasSuffix(text, "\n-- Not found: users, missing\n"), text)
+	})
+
+	t.Run("invalid objectType", func(t *testing.T) {
+		result, err := handler.ShowCreate(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"objectNames": []interface{}{"users"},
+				"objectType":  "index",
+			}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ColumnImpact holds every schema object that references a column
+type ColumnImpact struct {
+	Table            string            `json:"table"`
+	Column           string            `json:"column"`
+	Indexes          []IndexInfo       `json:"indexes"` // Including PRIMARY and unique keys
+	OutgoingFKs      []DependencyEdge  `json:"outgoingForeignKeys"`
+	IncomingFKs      []DependencyEdge  `json:"incomingForeignKeys"`
+	GeneratedColumns []GeneratedColumn `json:"generatedColumns"`
+	Views            []SchemaObject    `json:"views"`
+	Triggers         []SchemaObject    `json:"triggers"`
+	Routines         []SchemaObject    `json:"routines"`
+}
+
+// OutgoingForeignKeys returns the foreign keys of the table that include the column
+func OutgoingForeignKeys(table TableSummary, column string) []DependencyEdge {
+	var edges []DependencyEdge
+	for _, fk := range table.FK {
+		if containsFold(fk.Columns, column) {
+			edges = append(edges, DependencyEdge{Table: table.Name, FK: fk})
+		}
+	}
+	return edges
+}
+
+// IncomingForeignKeys returns the foreign keys of any table that reference the column
+func IncomingForeignKeys(tables []TableSummary, tableName string, column string) []DependencyEdge {
+	var edges []DependencyEdge
+	for _, t := range tables {
+		for _, fk := range t.FK {
+			if fk.RefTable == tableName && containsFold(fk.RefColumns, column) {
+				edges = append(edges, DependencyEdge{Table: t.Name, FK: fk})
+			}
+		}
+	}
+	return edges
+}
+
+// GeneratedColumnsReferencing returns the generated columns whose expression references the column
+func GeneratedColumnsReferencing(columns []GeneratedColumn, column string) []GeneratedColumn {
+	var result []GeneratedColumn
+	for _, c := range columns {
+		if !strings.EqualFold(c.Name, column) && referencesIdentifier(c.Expression, column) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// ObjectsReferencing returns the views, triggers or routines whose definition may reference the column.
+// Definitions are matched textually: triggers on the table itself only need to mention the column
+// (e.g. NEW.col), other objects need to mention both the table and the column.
+func ObjectsReferencing(objects []SchemaObject, tableName string, column string) []SchemaObject {
+	var result []SchemaObject
+	for _, o := range objects {
+		if !referencesIdentifier(o.Definition, column) {
+			continue
+		}
+		if o.Type == "TRIGGER" && o.Table == tableName || referencesIdentifier(o.Definition, tableName) {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// referencesIdentifier reports whether the SQL text contains the identifier as a whole word, with or without backquotes
+func referencesIdentifier(text string, identifier string) bool {
+	re := regexp.MustCompile(`(?i)(^|[^A-Za-z0-9_$])` + regexp.QuoteMeta(identifier) + `($|[^A-Za-z0-9_$])`)
+	return re.MatchString(text)
+}
+
+// containsFold reports whether the list contains the name, ignoring case like MySQL column names
+func containsFold(list []string, name string) bool {
+	return slices.ContainsFunc(list, func(s string) bool { return strings.EqualFold(s, name) })
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferencesIdentifier(t *testing.T) {
+	assert.True(t, referencesIdentifier("select `db`.`orders`.`price` AS `price`", "price"))
+	assert.True(t, referencesIdentifier("SET NEW.price = 0", "price"))
+	assert.True(t, referencesIdentifier("price", "PRICE"))
+	assert.False(t, referencesIdentifier("SET NEW.price_with_tax = 0", "price"))
+	assert.False(t, referencesIdentifier("SET NEW.unit_price = 0", "price"))
+}
+
+func TestObjectsReferencing(t *testing.T) {
+	objects := []SchemaObject{
+		{Name: "v_orders", Type: "VIEW", Definition: "select `orders`.`price` from `orders`"},
+		{Name: "v_items", Type: "VIEW", Definition: "select `items`.`price` from `items`"},
+		{Name: "trg_orders", Type: "TRIGGER", Table: "orders", Definition: "SET NEW.price = 0"},
+		{Name: "trg_items", Type: "TRIGGER", Table: "items", Definition: "SET NEW.price = 0"},
+		{Name: "p_update", Type: "PROCEDURE", Definition: "BEGIN UPDATE orders SET status = 1; END"},
+	}
+
+	result := ObjectsReferencing(objects, "orders", "price")
+
+	assert.Equal(t, []SchemaObject{objects[0], objects[2]}, result)
+}
+
+func TestIncomingAndOutgoingForeignKeys(t *testing.T) {
+	tables := []TableSummary{
+		{Name: "order_items", FK: []ForeignKey{
+			{Name: "fk_order", Columns: []string{"order_id"}, RefTable: "orders", RefColumns: []string{"id"}},
+		}},
+		{Name: "orders", FK: []ForeignKey{
+			{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+		}},
+	}
+
+	assert.Equal(t, []DependencyEdge{{Table: "order_items", FK: tables[0].FK[0]}}, IncomingForeignKeys(tables, "orders", "id"))
+	assert.Empty(t, IncomingForeignKeys(tables, "orders", "user_id"))
+	assert.Equal(t, []DependencyEdge{{Table: "orders", FK: tables[1].FK[0]}}, OutgoingForeignKeys(tables[1], "user_id"))
+	assert.Empty(t, OutgoingForeignKeys(tables[1], "id"))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InferenceConfig controls the heuristic inference of relationships that are not declared as foreign keys
+type InferenceConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	MinConfidence float64 `yaml:"minConfidence"`
+}
+
+// defaultInferMinConfidence is the minimum confidence used when none is configured
+const defaultInferMinConfidence = 0.6
+
+// inferenceCandidate is a possible reference target for a column
+type inferenceCandidate struct {
+	table  string
+	column string
+}
+
+// InferForeignKeys proposes relationships from naming conventions, matching types and index presence.
+// tables must already contain PK, UK and declared FK information. The result is keyed by table name and
+// only contains relationships whose confidence is at least minConfidence.
+func InferForeignKeys(tables []TableSummary, columns map[string][]ColumnInfo, indexed map[string]map[string]bool, minConfidence float64) map[string][]ForeignKey {
+	tableByName := make(map[string]TableSummary, len(tables))
+	for _, t := range tables {
+		tableByName[strings.ToLower(t.Name)] = t
+	}
+
+	inferred := make(map[string][]ForeignKey)
+	for _, table := range tables {
+		for _, col := range columns[table.Name] {
+			if isDeclaredFKColumn(table.FK, col.Name) {
+				continue
+			}
+
+			var best *ForeignKey
+			for _, cand := range referenceCandidates(col.Name, tableByName) {
+				if strings.EqualFold(cand.table, table.Name) && strings.EqualFold(cand.column, col.Name) {
+					// A column never references itself
+					continue
+				}
+
+				confidence, ok := scoreCandidate(table.Name, col, cand, tableByName, columns, indexed)
+				if !ok || confidence < minConfidence {
+					continue
+				}
+				if best == nil || confidence > best.Confidence {
+					best = &ForeignKey{
+						Name:       "inferred_" + table.Name + "_" + col.Name,
+						Columns:    []string{col.Name},
+						RefTable:   cand.table,
+						RefColumns: []string{cand.column},
+						Origin:     FKOriginInferred,
+						Confidence: confidence,
+					}
+				}
+			}
+
+			if best != nil {
+				inferred[table.Name] = append(inferred[table.Name], *best)
+			}
+		}
+	}
+
+	return inferred
+}
+
+// ApplyInferredForeignKeys appends inferred relationships to the FK list of each table
+func ApplyInferredForeignKeys(tables []TableSummary, inferred map[string][]ForeignKey) {
+	for i := range tables {
+		tables[i].FK = append(tables[i].FK, inferred[tables[i].Name]...)
+	}
+}
+
+// isDeclaredFKColumn reports whether the column is already the first column of a known foreign key
+func isDeclaredFKColumn(fks []ForeignKey, column string) bool {
+	for _, fk := range fks {
+		if len(fk.Columns) > 0 && strings.EqualFold(fk.Columns[0], column) {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceCandidates lists the columns a column may reference, based on naming conventions:
+//   - <table>_<column> references <table>.<column> (e.g. user_id -> users.id, shop_code -> shops.code)
+//   - a column with the same name as the single-column key of another table references it (e.g. product_code -> products.product_code)
+func referenceCandidates(columnName string, tableByName map[string]TableSummary) []inferenceCandidate {
+	var candidates []inferenceCandidate
+	lower := strings.ToLower(columnName)
+
+	if idx := strings.LastIndex(lower, "_"); idx > 0 && idx < len(lower)-1 {
+		prefix, suffix := lower[:idx], lower[idx+1:]
+		for _, name := range tableNameVariants(prefix) {
+			if t, ok := tableByName[name]; ok {
+				candidates = append(candidates, inferenceCandidate{table: t.Name, column: suffix})
+			}
+		}
+	}
+
+	if lower != "id" {
+		names := make([]string, 0, len(tableByName))
+		for name := range tableByName {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			t := tableByName[name]
+			if len(t.PK) == 1 && strings.EqualFold(t.PK[0], columnName) {
+				candidates = append(candidates, inferenceCandidate{table: t.Name, column: t.PK[0]})
+			}
+		}
+	}
+
+	return candidates
+}
+
+// tableNameVariants returns the singular and plural table names a column prefix may refer to
+func tableNameVariants(prefix string) []string {
+	variants := []string{prefix, prefix + "s", prefix + "es"}
+	if strings.HasSuffix(prefix, "y") {
+		variants = append(variants, strings.TrimSuffix(prefix, "y")+"ies")
+	}
+	return variants
+}
+
+// scoreCandidate computes the confidence of a candidate reference. It returns false when the candidate is impossible
+func scoreCandidate(tableName string, col ColumnInfo, cand inferenceCandidate, tableByName map[string]TableSummary, columns map[string][]ColumnInfo, indexed map[string]map[string]bool) (float64, bool) {
+	target := tableByName[strings.ToLower(cand.table)]
+
+	// The referenced column must exist and uniquely identify a row
+	var refCol *ColumnInfo
+	for i, c := range columns[target.Name] {
+		if strings.EqualFold(c.Name, cand.column) {
+			refCol = &columns[target.Name][i]
+			break
+		}
+	}
+	if refCol == nil {
+		return 0, false
+	}
+
+	confidence := 0.5
+
+	switch {
+	case len(target.PK) == 1 && strings.EqualFold(target.PK[0], refCol.Name):
+		confidence += 0.1
+	case hasSingleColumnUK(target.UK, refCol.Name):
+		confidence += 0.05
+	default:
+		return 0, false
+	}
+
+	srcType, refType := normalizeColumnType(col.Type), normalizeColumnType(refCol.Type)
+	switch {
+	case srcType == refType:
+		confidence += 0.2
+	case columnTypeFamily(srcType) != "" && columnTypeFamily(srcType) == columnTypeFamily(refType):
+		confidence += 0.05
+	default:
+		return 0, false
+	}
+
+	if indexed[tableName][col.Name] {
+		confidence += 0.2
+	}
+
+	return math.Round(math.Min(confidence, 1.0)*100) / 100, true
+}
+
+// hasSingleColumnUK reports whether the column alone is a unique key
+func hasSingleColumnUK(uks []UniqueKey, column string) bool {
+	for _, uk := range uks {
+		if len(uk.Columns) == 1 && strings.EqualFold(uk.Columns[0], column) {
+			return true
+		}
+	}
+	return false
+}
+
+var integerDisplayWidth = regexp.MustCompile(`^((?:tiny|small|medium|big)?int)\(\d+\)`)
+
+// normalizeColumnType removes differences that do not matter when comparing types, such as the display width of integers
+func normalizeColumnType(columnType string) string {
+	t := strings.ToLower(strings.TrimSpace(columnType))
+	t = strings.TrimSpace(strings.ReplaceAll(t, "zerofill", ""))
+	return integerDisplayWidth.ReplaceAllString(t, "$1")
+}
+
+// columnTypeFamily groups compatible types so that e.g. int and bigint can still be joined
+func columnTypeFamily(columnType string) string {
+	base := columnType
+	if idx := strings.IndexAny(base, "( "); idx >= 0 {
+		base = base[:idx]
+	}
+	switch base {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return "integer"
+	case "char", "varchar":
+		return "string"
+	case "binary", "varbinary":
+		return "binary"
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferForeignKeys(t *testing.T) {
+	tables := []TableSummary{
+		{Name: "categories", PK: []string{"category_code"}},
+		{Name: "orders", PK: []string{"id"}},
+		{Name: "shops", PK: []string{"id"}, UK: []UniqueKey{{Name: "uk_code", Columns: []string{"code"}}}},
+		{Name: "users", PK: []string{"id"}},
+	}
+	columns := map[string][]ColumnInfo{
+		"categories": {
+			{Name: "category_code", Type: "varchar(50)"},
+		},
+		"orders": {
+			{Name: "id", Type: "int"},
+			{Name: "user_id", Type: "int(11)"},
+			{Name: "shop_code", Type: "varchar(50)"},
+			{Name: "category_code", Type: "varchar(50)"},
+			{Name: "note_id", Type: "varchar(50)"},
+		},
+		"shops": {
+			{Name: "id", Type: "int"},
+			{Name: "code", Type: "varchar(50)"},
+		},
+		"users": {
+			{Name: "id", Type: "int"},
+		},
+	}
+	indexed := map[string]map[string]bool{
+		"orders": {"id": true, "user_id": true},
+	}
+
+	inferred := InferForeignKeys(tables, columns, indexed, 0.5)
+
+	assert.Equal(t, map[string][]ForeignKey{
+		"orders": {
+			{Name: "inferred_orders_user_id", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, Origin: FKOriginInferred, Confidence: 1.0},
+			{Name: "inferred_orders_shop_code", Columns: []string{"shop_code"}, RefTable: "shops", RefColumns: []string{"code"}, Origin: FKOriginInferred, Confidence: 0.75},
+			{Name: "inferred_orders_category_code", Columns: []string{"category_code"}, RefTable: "categories", RefColumns: []string{"category_code"}, Origin: FKOriginInferred, Confidence: 0.8},
+		},
+	}, inferred)
+}
+
+func TestInferForeignKeys_SkipsDeclaredAndLowConfidence(t *testing.T) {
+	tables := []TableSummary{
+		{Name: "orders", PK: []string{"id"}, FK: []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}},
+		{Name: "users", PK: []string{"id"}},
+		{Name: "shops", PK: []string{"id"}},
+	}
+	columns := map[string][]ColumnInfo{
+		"orders": {
+			{Name: "id", Type: "int"},
+			{Name: "user_id", Type: "int"},
+			{Name: "shop_id", Type: "bigint"},
+		},
+		"users": {{Name: "id", Type: "int"}},
+		"shops": {{Name: "id", Type: "int"}},
+	}
+
+	inferred := InferForeignKeys(tables, columns, nil, 0.7)
+
+	assert.Empty(t, inferred, "declared keys and low confidence candidates should not be inferred")
+}
+
+func TestNormalizeColumnType(t *testing.T) {
+	assert.Equal(t, "int", normalizeColumnType("INT(11)"))
+	assert.Equal(t, "bigint unsigned", normalizeColumnType("bigint(20) unsigned"))
+	assert.Equal(t, "varchar(50)", normalizeColumnType("varchar(50)"))
+}
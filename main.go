@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -13,56 +20,191 @@ import (
 const Version = "1.1.1"
 
 func main() {
-	dbConfig, err := loadDBConfig()
+	if len(os.Args) > 1 && os.Args[1] == "dump" {
+		if err := runDump(os.Args[2:]); err != nil {
+			log.Fatalf("Failed to dump schema: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfig(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiff(os.Args[2:], os.Stdout); err != nil {
+			if errors.Is(err, errSchemasDiffer) {
+				os.Exit(1)
+			}
+			log.Fatalf("Failed to diff schemas: %v", err)
+		}
+		return
+	}
+
+	if err := runServer(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runServer starts the MCP server on the transport selected by the flags, which override the environment
+// variables and the config file:
+//
+//	mysql-schema-explorer-mcp --config config.yaml --transport http --listen :8080 --base-path /mcp
+func runServer(args []string) error {
+	flags := flag.NewFlagSet("mysql-schema-explorer-mcp", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "The config file (default: CONFIG_FILE)")
+	var transport TransportConfig
+	flags.StringVar(&transport.Type, "transport", "", "The transport: stdio or http (default: MCP_TRANSPORT, or stdio)")
+	flags.StringVar(&transport.Listen, "listen", "", "The address to listen on with the http transport (default: MCP_LISTEN, or "+defaultListenAddress+")")
+	flags.StringVar(&transport.BasePath, "base-path", "", "The path of the streamable HTTP endpoint; SSE is served under it (default: MCP_BASE_PATH, or "+defaultBasePath+")")
+	flags.StringVar(&transport.TokensFile, "auth-tokens-file", "", "A file of identity:token lines accepted as bearer tokens (default: MCP_AUTH_TOKENS_FILE)")
+	flags.StringVar(&transport.TLSCert, "tls-cert", "", "The certificate file to serve HTTPS with (default: MCP_TLS_CERT)")
+	flags.StringVar(&transport.TLSKey, "tls-key", "", "The private key file of --tls-cert (default: MCP_TLS_KEY)")
+	flags.StringVar(&transport.ClientCA, "tls-client-ca", "", "The CA file to verify client certificates with (default: MCP_TLS_CLIENT_CA)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	config, err := readConfig(*configPath)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return err
 	}
+	overrideString(&config.Transport.Type, transport.Type)
+	overrideString(&config.Transport.Listen, transport.Listen)
+	overrideString(&config.Transport.BasePath, transport.BasePath)
+	overrideString(&config.Transport.TokensFile, transport.TokensFile)
+	overrideString(&config.Transport.TLSCert, transport.TLSCert)
+	overrideString(&config.Transport.TLSKey, transport.TLSKey)
+	overrideString(&config.Transport.ClientCA, transport.ClientCA)
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	transport = config.Transport
 
-	sqlDB, err := connectDB(dbConfig)
+	var authenticator *authenticator
+	var tlsConfig *tls.Config
+	if transport.Type == transportHTTP {
+		authenticator, tlsConfig, err = loadHTTPAuth(transport.authConfig)
+		if err != nil {
+			return err
+		}
+		if authenticator == nil && !isLoopbackAddress(transport.Listen) {
+			return fmt.Errorf("the http transport on %s requires authentication: set MCP_AUTH_TOKENS, --auth-tokens-file or --tls-client-ca", transport.Listen)
+		}
+	}
+
+	connections, closeDB, err := setupConnections(config)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return err
 	}
-	defer sqlDB.Close()
+	defer closeDB()
 
-	if err := sqlDB.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	s := newMCPServer(connections)
+
+	if transport.Type == transportHTTP {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return serveHTTP(ctx, s, transport.Listen, transport.BasePath, authenticator, tlsConfig)
+	}
+
+	if err := server.ServeStdio(s); err != nil {
+		fmt.Printf("Server error: %v\n", err)
+	}
+	return nil
+}
+
+// newMCPServer creates the MCP server with every tool of the connections
+func newMCPServer(connections *Connections) *server.MCPServer {
+	defaultHandler, _ := connections.Handler("")
+	fixedDBName := defaultHandler.fixedDBName
+
+	// With several connections, every tool takes the connection to use
+	var connectionOptions []mcp.ToolOption
+	if connections.Multiple() {
+		fixedDBName = ""
+		connectionOptions = append(connectionOptions, mcp.WithString("connection",
+			mcp.Enum(connections.Names()...),
+			mcp.Description("The connection to use (see list_connections). Defaults to "+connections.Names()[0]+"."),
+		))
+	}
+
+	// dbName is required unless the database of every connection is fixed
+	dbOptions := connectionOptions
+	switch fixed := connections.fixedDatabases(); {
+	case fixed == 0:
+		dbOptions = append(dbOptions, mcp.WithString("dbName",
+			mcp.Required(),
+			mcp.Description("The name of the database to retrieve information from."),
+		))
+	case fixed < len(connections.Names()):
+		dbOptions = append(dbOptions, mcp.WithString("dbName",
+			mcp.Description("The name of the database to retrieve information from. Required for connections without a fixed database, ignored otherwise."),
+		))
 	}
 
-	// Initialize DB layer and handler
-	db := NewDB(sqlDB)
-	fixedDBName := os.Getenv("DB_NAME")
-	handler := NewHandler(db, fixedDBName)
+	// Every tool accepts the output format
+	formatOption := mcp.WithString("format",
+		mcp.Enum(formatText, formatJSON),
+		mcp.Description("The output format. \"text\" (default) is a compact text format, \"json\" is structured data."),
+	)
 
 	s := server.NewMCPServer(
 		"mysql-schema-mcp",
 		Version,
+		server.WithToolHandlerMiddleware(logToolCall),
 	)
 
 	// Build list_tables tool options
 	listTablesOpts := []mcp.ToolOption{
 		mcp.WithDescription("Returns a list of table information in the MySQL database."),
 	}
-	if fixedDBName == "" {
-		listTablesOpts = append(listTablesOpts, mcp.WithString("dbName",
-			mcp.Required(),
-			mcp.Description("The name of the database to retrieve information from."),
-		))
-	}
+	listTablesOpts = append(listTablesOpts, dbOptions...)
+	listTablesOpts = append(listTablesOpts,
+		mcp.WithNumber("maxTokens",
+			mcp.Description("The approximate maximum number of tokens of the output. When the output exceeds it, tables are omitted and a cursor to get the rest is returned."),
+		),
+		mcp.WithNumber("maxBytes",
+			mcp.Description("The maximum number of bytes of the output. When the output exceeds it, tables are omitted and a cursor to get the rest is returned."),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("The cursor returned by a previous call to get the tables that were omitted. The cursor keeps the filter of that call, so the filter arguments don't need to be repeated."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("The maximum number of tables to return. When more tables match, a cursor to get the rest is returned."),
+		),
+		mcp.WithString("prefix",
+			mcp.Description("Only return tables whose name starts with this prefix."),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Only return tables whose name matches this SQL LIKE pattern (e.g. %_log)."),
+		),
+		mcp.WithBoolean("hasComment",
+			mcp.Description("Only return tables with (true) or without (false) a table comment."),
+		),
+		mcp.WithString("detail",
+			mcp.Enum(listDetails...),
+			mcp.Description("The level of detail. \"names\" and \"names+comments\" are cheap overviews, \"keys\" (default) adds primary, unique and foreign keys, \"full\" also adds the columns."),
+		),
+		formatOption,
+	)
 	s.AddTool(
 		mcp.NewTool("list_tables", listTablesOpts...),
-		handler.ListTables,
+		connections.route((*Handler).ListTables),
 	)
 
 	// Build describe_tables tool options
 	describeTablesOpts := []mcp.ToolOption{
 		mcp.WithDescription("Returns detailed information for the specified tables."),
 	}
-	if fixedDBName == "" {
-		describeTablesOpts = append(describeTablesOpts, mcp.WithString("dbName",
-			mcp.Required(),
-			mcp.Description("The name of the database to retrieve information from."),
-		))
-	}
+	describeTablesOpts = append(describeTablesOpts, dbOptions...)
 	describeTablesOpts = append(describeTablesOpts, mcp.WithArray(
 		"tableNames",
 		mcp.Items(
@@ -73,38 +215,231 @@ func main() {
 		mcp.Required(),
 		mcp.Description("The names of the tables to retrieve detailed information for (multiple names can be specified)."),
 	))
+	describeTablesOpts = append(describeTablesOpts, formatOption)
 	s.AddTool(
 		mcp.NewTool("describe_tables", describeTablesOpts...),
-		handler.DescribeTables,
+		connections.route((*Handler).DescribeTables),
 	)
 
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Server error: %v\n", err)
+	// Build table_dependency_order tool options
+	dependencyOrderOpts := []mcp.ToolOption{
+		mcp.WithDescription("Returns tables in FK-safe insertion order (parents before children) and deletion order (children before parents), reporting foreign key cycles and self-references."),
+	}
+	dependencyOrderOpts = append(dependencyOrderOpts, dbOptions...)
+	dependencyOrderOpts = append(dependencyOrderOpts, formatOption)
+	s.AddTool(
+		mcp.NewTool("table_dependency_order", dependencyOrderOpts...),
+		connections.route((*Handler).TableDependencyOrder),
+	)
+
+	// Build analyze_column_impact tool options
+	columnImpactOpts := []mcp.ToolOption{
+		mcp.WithDescription("Reports every index, unique key, foreign key (incoming and outgoing), generated column, view, trigger and routine that references a column. Use this before dropping or renaming a column."),
+	}
+	columnImpactOpts = append(columnImpactOpts, dbOptions...)
+	columnImpactOpts = append(columnImpactOpts, mcp.WithString("column",
+		mcp.Required(),
+		mcp.Description("The column to analyze in table.column format (e.g. orders.user_id)."),
+	))
+	columnImpactOpts = append(columnImpactOpts, formatOption)
+	s.AddTool(
+		mcp.NewTool("analyze_column_impact", columnImpactOpts...),
+		connections.route((*Handler).AnalyzeColumnImpact),
+	)
+
+	// Build show_create tool options
+	showCreateOpts := []mcp.ToolOption{
+		mcp.WithDescription("Returns the exact SHOW CREATE output (DDL) of the specified tables, views, procedures, functions and triggers. Use this when writing ALTER statements."),
+	}
+	showCreateOpts = append(showCreateOpts, dbOptions...)
+	showCreateOpts = append(showCreateOpts,
+		mcp.WithArray(
+			"objectNames",
+			mcp.Items(
+				map[string]interface{}{
+					"type": "string",
+				},
+			),
+			mcp.Required(),
+			mcp.Description("The names of the objects to retrieve the DDL for (multiple names can be specified)."),
+		),
+		mcp.WithString("objectType",
+			mcp.Enum(objectTypes...),
+			mcp.Description("The type of the objects. When omitted, every object with the name is returned."),
+		),
+		mcp.WithBoolean("stripNoise",
+			mcp.Description("Remove the AUTO_INCREMENT= table option and DEFINER= clauses, which depend on the server rather than the schema."),
+		),
+		formatOption,
+	)
+	s.AddTool(
+		mcp.NewTool("show_create", showCreateOpts...),
+		connections.route((*Handler).ShowCreate),
+	)
+
+	// Build diff_schemas tool options
+	diffSchemasOpts := []mcp.ToolOption{
+		mcp.WithDescription("Compares two databases on the same server (e.g. staging and production), or a database and a stored snapshot, and reports added, removed and changed tables, columns (type, nullability, default, comment), primary keys, unique keys, indexes and foreign keys."),
+	}
+	diffSchemasOpts = append(diffSchemasOpts, connectionOptions...)
+	// A fixed database can only be compared with a snapshot
+	sourceDbNameOptions := []mcp.PropertyOption{
+		mcp.Description("The database to compare from. Objects that exist only in it are reported as added."),
+	}
+	targetDbNameDescription := "The database to compare against. Objects that exist only in it are reported as removed."
+	if fixedDBName != "" {
+		sourceDbNameOptions[0] = mcp.Description("The database to compare from. Objects that exist only in it are reported as added. Only " + fixedDBName + " (the default) can be used.")
+		targetDbNameDescription += " Only " + fixedDBName + " (the default) can be used, so compare it with targetSnapshot."
+	} else if connections.fixedDatabases() > 0 {
+		sourceDbNameOptions[0] = mcp.Description("The database to compare from. Objects that exist only in it are reported as added. A connection with a fixed database only allows that database (the default).")
+		targetDbNameDescription += " A connection with a fixed database only allows that database (the default), so compare it with targetSnapshot."
+	} else {
+		sourceDbNameOptions = append(sourceDbNameOptions, mcp.Required())
+	}
+	diffSchemasOpts = append(diffSchemasOpts,
+		mcp.WithString("sourceDbName", sourceDbNameOptions...),
+	)
+	diffSchemasOpts = append(diffSchemasOpts,
+		mcp.WithString("targetDbName",
+			mcp.Description(targetDbNameDescription),
+		),
+		mcp.WithString("format",
+			mcp.Enum(formatText, formatJSON, formatDDL),
+			mcp.Description("The output format. \"text\" (default) is a compact text format, \"json\" is structured data, \"ddl\" is a draft of the ALTER/CREATE/DROP statements that would bring the target in line with the source, for review. The statements are never executed."),
+		),
+	)
+	// Snapshots can only be read from the snapshot directory
+	if defaultHandler.snapshotDir != "" {
+		diffSchemasOpts = append(diffSchemasOpts,
+			mcp.WithString("targetSnapshot",
+				mcp.Description("A snapshot file written by `dump --format json` (or a .sql DDL file) in the snapshot directory of the server, e.g. \"release.json\", to compare against instead of targetDbName, e.g. to find what changed since the snapshot was taken."),
+			),
+		)
+	}
+	// A fixed database has nothing to be compared with without snapshots
+	if defaultHandler.snapshotDir != "" || connections.fixedDatabases() < len(connections.list) {
+		s.AddTool(
+			mcp.NewTool("diff_schemas", diffSchemasOpts...),
+			connections.route((*Handler).DiffSchemas),
+		)
+	}
+
+	if connections.Multiple() {
+		s.AddTool(
+			mcp.NewTool("list_connections",
+				mcp.WithDescription("Returns the database connections that the other tools can use with the connection argument."),
+				formatOption,
+			),
+			connections.ListConnections,
+		)
 	}
+
+	return s
+}
+
+// setupConnections connects to the databases of the connections (or loads the schema snapshots in offline mode)
+// and creates a handler per connection. The returned function closes every connection
+func setupConnections(config Config) (*Connections, func(), error) {
+	opts, err := loadHandlerOptions(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var list []Connection
+	var closers []func()
+	closeAll := func() {
+		for _, closeDB := range closers {
+			closeDB()
+		}
+	}
+	for _, conn := range config.Connections {
+		db, closeDB, err := openConnection(conn)
+		if err != nil {
+			closeAll()
+			if len(config.Connections) == 1 {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("connection %s: %w", conn.Name, err)
+		}
+		closers = append(closers, closeDB)
+		list = append(list, Connection{
+			Name:        conn.Name,
+			Description: conn.Description,
+			Handler:     NewHandler(db, conn.Database, append([]HandlerOption{WithConnectionName(conn.Name)}, opts...)...),
+		})
+	}
+	return NewConnections(list...), closeAll, nil
 }
 
-func loadDBConfig() (DBConfig, error) {
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "localhost"
+// loadHandlerOptions loads the files of the configuration and returns the handler options shared by every connection
+func loadHandlerOptions(config Config) ([]HandlerOption, error) {
+	var virtualFKs []VirtualForeignKey
+	if config.VirtualForeignKeysFile != "" {
+		var err error
+		virtualFKs, err = LoadVirtualForeignKeys(config.VirtualForeignKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load virtual foreign keys: %w", err)
+		}
+	}
+
+	var policies []AccessPolicy
+	if config.AccessPolicyFile != "" {
+		var err error
+		policies, err = LoadAccessPolicies(config.AccessPolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load access policies: %w", err)
+		}
 	}
 
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "3306"
+	if config.SnapshotDir != "" {
+		if info, err := os.Stat(config.SnapshotDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("snapshotDir (SNAPSHOT_DIR) must be a directory: %s", config.SnapshotDir)
+		}
 	}
 
-	user := os.Getenv("DB_USER")
-	if user == "" {
-		return DBConfig{}, fmt.Errorf("DB_USER environment variable is not set")
+	templates, err := NewTemplates(config.Output.TemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
 	}
 
-	password := os.Getenv("DB_PASSWORD")
+	opts := []HandlerOption{
+		WithInference(config.Inference),
+		WithVirtualForeignKeys(virtualFKs),
+		WithTemplates(templates),
+		WithListTablesMaxBytes(config.Output.ListTablesMaxTokens * bytesPerToken),
+		WithObjectFilter(config.Filters),
+		WithSnapshotDir(config.SnapshotDir),
+	}
+	if policies != nil {
+		opts = append(opts, WithAccessPolicies(policies))
+	}
+	return opts, nil
+}
+
+// openSnapshotDB serves the schema snapshot file in offline mode
+func openSnapshotDB(path string) (DB, func(), error) {
+	snapshots, err := LoadSnapshots(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load schema snapshot: %w", err)
+	}
+	db, err := NewSnapshotDB(snapshots)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load schema snapshot: %w", err)
+	}
+	return db, func() {}, nil
+}
+
+// openMySQLDB connects to MySQL with a pool of its own
+func openMySQLDB(dbConfig DBConfig) (DB, func(), error) {
+	sqlDB, err := connectDB(dbConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, nil, fmt.Errorf("failed to ping database: %w", err)
+	}
 
-	return DBConfig{
-		Host:     host,
-		Port:     port,
-		User:     user,
-		Password: password,
-	}, nil
+	return NewDB(sqlDB), func() { sqlDB.Close() }, nil
 }
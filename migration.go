@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MigrationStatement is a statement of a migration draft generated from a schema diff
+type MigrationStatement struct {
+	Table     string `json:"table"`
+	Statement string `json:"statement"`
+	Warning   string `json:"warning,omitempty"` // Why the statement is destructive, empty when it is not
+}
+
+// BuildMigration generates the statements that would bring the target of the diff in line with the source.
+// The statements are ordered so that foreign keys are valid at every step:
+//
+//  1. Removed foreign keys are dropped, along with the kept foreign keys that a dropped index or a column type
+//     change would break (see blockingForeignKeys), then removed keys and indexes that they may have needed
+//  2. Added tables are created without foreign keys, parents before children
+//  3. Tables are altered: comments, columns, primary keys, then new keys and indexes
+//  4. Foreign keys are added, and the dropped kept ones added again, once every table, column and index they need exists
+//  5. Removed columns and tables are dropped, children before parents
+//
+// Destructive statements, which can lose data or fail on existing rows, have a warning.
+// AUTO_INCREMENT, generated column expressions and foreign key actions are not part of the diff and are not reproduced.
+// Foreign keys redacted by the object filter or the access policies are skipped
+func BuildMigration(diff SchemaDiff) []MigrationStatement {
+	var statements []MigrationStatement
+	add := func(table, warning, format string, args ...any) {
+		statements = append(statements, MigrationStatement{Table: table, Statement: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	// 1. Drop what is removed from kept tables, foreign keys first
+	for _, t := range diff.ChangedTables {
+		for _, fk := range t.RemovedForeignKeys {
+			add(t.Name, "", "ALTER TABLE %s DROP FOREIGN KEY %s;", quoteIdentifier(t.Name), quoteIdentifier(fk.Name))
+		}
+	}
+	blocking := blockingForeignKeys(diff)
+	for _, fk := range blocking {
+		add(fk.Table, "", "ALTER TABLE %s DROP FOREIGN KEY %s;", quoteIdentifier(fk.Table), quoteIdentifier(fk.Name))
+	}
+	for _, t := range diff.ChangedTables {
+		for _, uk := range t.RemovedUniqueKeys {
+			add(t.Name, "", "DROP INDEX %s ON %s;", quoteIdentifier(uk.Name), quoteIdentifier(t.Name))
+		}
+		for _, idx := range t.RemovedIndexes {
+			add(t.Name, "", "DROP INDEX %s ON %s;", quoteIdentifier(idx.Name), quoteIdentifier(t.Name))
+		}
+	}
+
+	// 2. Create the added tables
+	var addedSummaries []TableSummary
+	for _, t := range diff.AddedTables {
+		addedSummaries = append(addedSummaries, TableSummary{Name: t.Name, FK: t.ForeignKeys})
+	}
+	for _, name := range BuildDependencyOrder(addedSummaries).InsertionOrder {
+		for _, t := range diff.AddedTables {
+			if t.Name == name {
+				add(t.Name, "", "%s", createTableStatement(t))
+			}
+		}
+	}
+
+	// 3. Alter the kept tables
+	for _, t := range diff.ChangedTables {
+		table := quoteIdentifier(t.Name)
+		if t.Comment != nil {
+			add(t.Name, "", "ALTER TABLE %s COMMENT = %s;", table, quoteString(t.Comment.Source))
+		}
+		for _, c := range t.AddedColumns {
+			warning := ""
+			if c.IsNullable == "NO" && !c.Default.Valid {
+				warning = "adding a NOT NULL column without a default fills existing rows with the implicit default"
+			}
+			add(t.Name, warning, "ALTER TABLE %s ADD COLUMN %s;", table, columnDefinition(c))
+		}
+		for _, c := range t.ChangedColumns {
+			add(t.Name, columnChangeWarning(c), "ALTER TABLE %s MODIFY COLUMN %s;", table, columnDefinition(c.Source))
+		}
+		if pk := t.PrimaryKey; pk != nil {
+			switch {
+			case len(pk.Target) == 0:
+				add(t.Name, "adding a primary key fails if existing rows have duplicate or NULL values",
+					"ALTER TABLE %s ADD PRIMARY KEY (%s);", table, quoteIdentifiers(pk.Source))
+			case len(pk.Source) == 0:
+				add(t.Name, "dropping the primary key fails if it has an AUTO_INCREMENT column",
+					"ALTER TABLE %s DROP PRIMARY KEY;", table)
+			default:
+				add(t.Name, "replacing the primary key fails if existing rows have duplicate or NULL values, or if the old key has an AUTO_INCREMENT column that no other key covers",
+					"ALTER TABLE %s DROP PRIMARY KEY, ADD PRIMARY KEY (%s);", table, quoteIdentifiers(pk.Source))
+			}
+		}
+		for _, uk := range t.AddedUniqueKeys {
+			add(t.Name, "creating a unique index fails if existing rows have duplicate values",
+				"CREATE UNIQUE INDEX %s ON %s (%s);", quoteIdentifier(uk.Name), table, quoteIdentifiers(uk.Columns))
+		}
+		for _, idx := range t.AddedIndexes {
+			add(t.Name, "", "CREATE %sINDEX %s ON %s (%s);", indexKeyword(idx), quoteIdentifier(idx.Name), table, quoteIdentifiers(idx.Columns))
+		}
+	}
+
+	// 4. Add the foreign keys of the added and the kept tables
+	addForeignKeys := func(table string, fks []ForeignKey) {
+		for _, fk := range fks {
+			// The referenced table of a redacted foreign key is unknown
+			if fk.Redacted {
+				continue
+			}
+			add(table, "", "ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s);",
+				quoteIdentifier(table), quoteIdentifier(fk.Name), quoteIdentifiers(fk.Columns),
+				quoteIdentifier(fk.RefTable), quoteIdentifiers(fk.RefColumns))
+		}
+	}
+	for _, t := range diff.AddedTables {
+		addForeignKeys(t.Name, t.ForeignKeys)
+	}
+	for _, t := range diff.ChangedTables {
+		addForeignKeys(t.Name, t.AddedForeignKeys)
+	}
+	for _, fk := range blocking {
+		addForeignKeys(fk.Table, []ForeignKey{fk.ForeignKey})
+	}
+
+	// 5. Drop the removed columns and tables
+	for _, t := range diff.ChangedTables {
+		for _, c := range t.RemovedColumns {
+			add(t.Name, "drops the data of the column", "ALTER TABLE %s DROP COLUMN %s;", quoteIdentifier(t.Name), quoteIdentifier(c.Name))
+		}
+	}
+	var removedSummaries []TableSummary
+	for _, t := range diff.RemovedTables {
+		removedSummaries = append(removedSummaries, TableSummary{Name: t.Name, FK: t.ForeignKeys})
+	}
+	for _, name := range BuildDependencyOrder(removedSummaries).DeletionOrder {
+		add(name, "drops the table and all of its data", "DROP TABLE %s;", quoteIdentifier(name))
+	}
+
+	return statements
+}
+
+// blockingForeignKeys returns the kept foreign keys that MySQL would not let the migration break: those whose
+// columns or referenced columns change type, and those whose index on either side is dropped.
+// Redacted foreign keys can't be added again and are left alone
+func blockingForeignKeys(diff SchemaDiff) []keptForeignKey {
+	changed := map[string]*TableDiff{}
+	for i := range diff.ChangedTables {
+		changed[diff.ChangedTables[i].Name] = &diff.ChangedTables[i]
+	}
+	breaks := func(t *TableDiff, columns []string) bool {
+		return t != nil && (typeChanged(t, columns) || indexDropped(t, columns))
+	}
+
+	var blocking []keptForeignKey
+	for _, fk := range diff.keptForeignKeys {
+		if !fk.Redacted && (breaks(changed[fk.Table], fk.Columns) || breaks(changed[fk.RefTable], fk.RefColumns)) {
+			blocking = append(blocking, fk)
+		}
+	}
+	return blocking
+}
+
+// typeChanged reports whether the type of any of the columns changes
+func typeChanged(t *TableDiff, columns []string) bool {
+	for _, c := range t.ChangedColumns {
+		if slices.Contains(c.Changes, "type") && slices.ContainsFunc(columns, func(name string) bool { return strings.EqualFold(name, c.Name) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexDropped reports whether a dropped primary key, unique key or index starts with the columns,
+// in which case a foreign key on the columns may need it
+func indexDropped(t *TableDiff, columns []string) bool {
+	startsWith := func(index []string) bool {
+		return len(index) >= len(columns) && slices.EqualFunc(index[:len(columns)], columns, strings.EqualFold)
+	}
+	if t.PrimaryKey != nil && len(t.PrimaryKey.Target) > 0 && startsWith(t.PrimaryKey.Target) {
+		return true
+	}
+	for _, uk := range t.RemovedUniqueKeys {
+		if startsWith(uk.Columns) {
+			return true
+		}
+	}
+	for _, idx := range t.RemovedIndexes {
+		if startsWith(idx.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// createTableStatement returns the CREATE TABLE statement of a table without its foreign keys
+func createTableStatement(t TableDetail) string {
+	var definitions []string
+	for _, c := range t.Columns {
+		definitions = append(definitions, columnDefinition(c))
+	}
+	if len(t.PrimaryKeys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", quoteIdentifiers(t.PrimaryKeys)))
+	}
+	for _, uk := range t.UniqueKeys {
+		definitions = append(definitions, fmt.Sprintf("UNIQUE KEY %s (%s)", quoteIdentifier(uk.Name), quoteIdentifiers(uk.Columns)))
+	}
+	for _, idx := range t.Indexes {
+		definitions = append(definitions, fmt.Sprintf("%sKEY %s (%s)", indexKeyword(idx), quoteIdentifier(idx.Name), quoteIdentifiers(idx.Columns)))
+	}
+
+	statement := fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", quoteIdentifier(t.Name), strings.Join(definitions, ",\n  "))
+	if t.Comment != "" {
+		statement += " COMMENT=" + quoteString(t.Comment)
+	}
+	return statement + ";"
+}
+
+// columnDefinition returns the definition of a column for CREATE TABLE and ALTER TABLE
+func columnDefinition(c ColumnInfo) string {
+	definition := quoteIdentifier(c.Name) + " " + c.Type
+	if c.IsNullable == "NO" {
+		definition += " NOT NULL"
+	} else {
+		definition += " NULL"
+	}
+	if c.Default.Valid {
+		definition += " DEFAULT " + defaultLiteral(c)
+	}
+	if c.Comment != "" {
+		definition += " COMMENT " + quoteString(c.Comment)
+	}
+	return definition
+}
+
+var (
+	numericLiteral    = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+	bitLiteral        = regexp.MustCompile(`^([bB]'[01]*'|[xX]'[0-9A-Fa-f]*'|0x[0-9A-Fa-f]+)$`)
+	timestampFunction = regexp.MustCompile(`(?i)^(CURRENT_TIMESTAMP|NOW|LOCALTIMESTAMP)(\([0-9]*\))?$`)
+	functionCall      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\(.*\)$`)
+	numericTypes      = regexp.MustCompile(`(?i)^(tinyint|smallint|mediumint|int|bigint|decimal|float|double|bit)\b`)
+	characterTypes    = regexp.MustCompile(`(?i)^(char|varchar|tinytext|text|mediumtext|longtext|enum|set)\b`)
+)
+
+// defaultLiteral returns the default value of a column as SQL. INFORMATION_SCHEMA doesn't quote string defaults,
+// so values are quoted unless they are numbers of a numeric column, bit or hex literals of a non-character column,
+// timestamp functions or expressions. MySQL 8 shows expression defaults such as uuid() without their parentheses,
+// so function calls are taken as expressions and parenthesized again
+func defaultLiteral(c ColumnInfo) string {
+	value := c.Default.String
+	switch {
+	case numericTypes.MatchString(c.Type) && numericLiteral.MatchString(value):
+		return value
+	case !characterTypes.MatchString(c.Type) && bitLiteral.MatchString(value):
+		return value
+	case timestampFunction.MatchString(value):
+		return value
+	case strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")"):
+		return value
+	case functionCall.MatchString(value):
+		return "(" + value + ")"
+	default:
+		return quoteString(value)
+	}
+}
+
+// columnChangeWarning returns why modifying the column is destructive, or an empty string
+func columnChangeWarning(c ColumnChange) string {
+	var warnings []string
+	for _, change := range c.Changes {
+		switch {
+		case change == "type":
+			warnings = append(warnings, fmt.Sprintf("changing the type from %s to %s can truncate values or fail on existing rows", c.Target.Type, c.Source.Type))
+		case change == "nullable" && c.Source.IsNullable == "NO":
+			warnings = append(warnings, "making the column NOT NULL fails on existing NULL values")
+		}
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// indexKeyword returns "UNIQUE " for unique indexes and an empty string otherwise
+func indexKeyword(idx IndexInfo) string {
+	if idx.Unique {
+		return "UNIQUE "
+	}
+	return ""
+}
+
+// quoteIdentifiers quotes and joins column names
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quoteString returns a SQL string literal
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+}
+
+// formatMigration renders the migration statements as a SQL script, with the destructive statements marked
+func formatMigration(diff SchemaDiff, statements []MigrationStatement) string {
+	var output strings.Builder
+	target := quoteIdentifier(diff.Target)
+	if diff.TargetFile != "" {
+		target += " (" + diff.TargetFile + ")"
+	}
+	fmt.Fprintf(&output, "-- Migration draft to bring %s in line with %s\n", target, quoteIdentifier(diff.Source))
+	output.WriteString("-- Generated from the schema diff for review. It has not been executed.\n")
+	output.WriteString("-- AUTO_INCREMENT, generated columns and foreign key actions (ON DELETE/ON UPDATE) are not reproduced.\n")
+
+	if len(statements) == 0 {
+		output.WriteString("\n-- No differences.\n")
+		return output.String()
+	}
+	for _, s := range statements {
+		output.WriteString("\n")
+		if s.Warning != "" {
+			fmt.Fprintf(&output, "-- DESTRUCTIVE: %s\n", s.Warning)
+		}
+		output.WriteString(s.Statement + "\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMigration(t *testing.T) {
+	source, err := ParseDDL(`
+CREATE TABLE users (
+    id INT PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE COMMENT 'User''s email'
+);
+CREATE TABLE orders (
+    id BIGINT PRIMARY KEY,
+    user_id INT NOT NULL,
+    coupon_id INT,
+    status VARCHAR(20) NOT NULL DEFAULT 'new',
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_coupon FOREIGN KEY (coupon_id) REFERENCES coupons(id)
+) COMMENT='Order header';
+CREATE TABLE coupons (
+    id INT PRIMARY KEY,
+    code VARCHAR(20) NOT NULL,
+    UNIQUE KEY uk_code (code)
+);
+`, "app_staging")
+	require.NoError(t, err)
+	target, err := ParseDDL(`
+CREATE TABLE users (
+    id INT PRIMARY KEY,
+    email VARCHAR(191) NULL COMMENT 'User''s email'
+);
+CREATE TABLE orders (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL,
+    legacy_id INT,
+    INDEX idx_user (user_id),
+    CONSTRAINT fk_legacy FOREIGN KEY (legacy_id) REFERENCES legacy_logs(id)
+) COMMENT='Order';
+CREATE TABLE legacy_logs (
+    id INT PRIMARY KEY,
+    run_id INT,
+    FOREIGN KEY (run_id) REFERENCES legacy_runs(id)
+);
+CREATE TABLE legacy_runs (id INT PRIMARY KEY);
+`, "app_production")
+	require.NoError(t, err)
+
+	diff := DiffSchemas(source[0], target[0])
+	statements := BuildMigration(diff)
+
+	var sqls []string
+	for _, s := range statements {
+		sqls = append(sqls, s.Statement)
+	}
+	assert.Equal(t, []string{
+		// Removed foreign keys and indexes first
+		"ALTER TABLE `orders` DROP FOREIGN KEY `fk_legacy`;",
+		"DROP INDEX `idx_user` ON `orders`;",
+		// Added tables without foreign keys
+		"CREATE TABLE `coupons` (\n  `id` int NOT NULL,\n  `code` varchar(20) NOT NULL,\n  PRIMARY KEY (`id`),\n  UNIQUE KEY `uk_code` (`code`)\n);",
+		// Changed tables
+		"ALTER TABLE `users` MODIFY COLUMN `email` varchar(255) NOT NULL COMMENT 'User''s email';",
+		"CREATE UNIQUE INDEX `email` ON `users` (`email`);",
+		"ALTER TABLE `orders` COMMENT = 'Order header';",
+		"ALTER TABLE `orders` ADD COLUMN `coupon_id` int NULL;",
+		"ALTER TABLE `orders` ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'new';",
+		"ALTER TABLE `orders` MODIFY COLUMN `id` bigint NOT NULL;",
+		// Foreign keys once the tables and columns exist
+		"ALTER TABLE `orders` ADD CONSTRAINT `fk_coupon` FOREIGN KEY (`coupon_id`) REFERENCES `coupons` (`id`);",
+		"ALTER TABLE `orders` ADD CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);",
+		// Removed columns and tables last, children before parents
+		"ALTER TABLE `orders` DROP COLUMN `legacy_id`;",
+		"DROP TABLE `legacy_logs`;",
+		"DROP TABLE `legacy_runs`;",
+	}, sqls)
+
+	warnings := map[string]string{}
+	for _, s := range statements {
+		if s.Warning != "" {
+			warnings[s.Statement] = s.Warning
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"ALTER TABLE `users` MODIFY COLUMN `email` varchar(255) NOT NULL COMMENT 'User''s email';": "changing the type from varchar(191) to varchar(255) can truncate values or fail on existing rows; making the column NOT NULL fails on existing NULL values",
+		"CREATE UNIQUE INDEX `email` ON `users` (`email`);":                                        "creating a unique index fails if existing rows have duplicate values",
+		"ALTER TABLE `orders` MODIFY COLUMN `id` bigint NOT NULL;":                                 "changing the type from int to bigint can truncate values or fail on existing rows",
+		"ALTER TABLE `orders` DROP COLUMN `legacy_id`;":                                            "drops the data of the column",
+		"DROP TABLE `legacy_logs`;":                                                                "drops the table and all of its data",
+		"DROP TABLE `legacy_runs`;":                                                                "drops the table and all of its data",
+	}, warnings)
+
+	assert.Empty(t, BuildMigration(DiffSchemas(source[0], source[0])))
+}
+
+func TestBuildMigration_PrimaryKey(t *testing.T) {
+	source, err := ParseDDL(`
+CREATE TABLE added (id INT NOT NULL, PRIMARY KEY (id));
+CREATE TABLE dropped (id INT NOT NULL);
+CREATE TABLE changed (tenant_id INT NOT NULL, id INT NOT NULL, PRIMARY KEY (tenant_id, id));
+`, "app_staging")
+	require.NoError(t, err)
+	target, err := ParseDDL(`
+CREATE TABLE added (id INT NOT NULL);
+CREATE TABLE dropped (id INT NOT NULL, PRIMARY KEY (id));
+CREATE TABLE changed (tenant_id INT NOT NULL, id INT NOT NULL, PRIMARY KEY (id));
+`, "app_production")
+	require.NoError(t, err)
+
+	warnings := map[string]string{}
+	for _, s := range BuildMigration(DiffSchemas(source[0], target[0])) {
+		warnings[s.Statement] = s.Warning
+	}
+	assert.Equal(t, map[string]string{
+		"ALTER TABLE `added` ADD PRIMARY KEY (`id`);": "adding a primary key fails if existing rows have duplicate or NULL values",
+		"ALTER TABLE `dropped` DROP PRIMARY KEY;":     "dropping the primary key fails if it has an AUTO_INCREMENT column",
+		"ALTER TABLE `changed` DROP PRIMARY KEY, ADD PRIMARY KEY (`tenant_id`, `id`);": "replacing the primary key fails if existing rows have duplicate or NULL values, " +
+			"or if the old key has an AUTO_INCREMENT column that no other key covers",
+	}, warnings)
+}
+
+func TestBuildMigration_BlockingForeignKeys(t *testing.T) {
+	source, err := ParseDDL(`
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE orders (
+    id INT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    INDEX idx_orders_user (user_id),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE TABLE coupons (id INT PRIMARY KEY, code VARCHAR(20), INDEX idx_code (code));
+CREATE TABLE order_coupons (
+    order_id INT NOT NULL,
+    coupon_id INT NOT NULL,
+    CONSTRAINT fk_order FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+`, "app_staging")
+	require.NoError(t, err)
+	target, err := ParseDDL(`
+CREATE TABLE users (id INT PRIMARY KEY);
+CREATE TABLE orders (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL,
+    INDEX idx_user (user_id),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE TABLE coupons (id INT PRIMARY KEY, code VARCHAR(10));
+CREATE TABLE order_coupons (
+    order_id INT NOT NULL,
+    coupon_id INT NOT NULL,
+    CONSTRAINT fk_order FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+`, "app_production")
+	require.NoError(t, err)
+
+	var sqls []string
+	for _, s := range BuildMigration(DiffSchemas(source[0], target[0])) {
+		sqls = append(sqls, s.Statement)
+	}
+	// fk_user needs idx_user, and its columns change type on both sides. fk_order is not affected
+	assert.Equal(t, []string{
+		"ALTER TABLE `orders` DROP FOREIGN KEY `fk_user`;",
+		"DROP INDEX `idx_user` ON `orders`;",
+		"ALTER TABLE `users` MODIFY COLUMN `id` bigint NOT NULL;",
+		"ALTER TABLE `orders` MODIFY COLUMN `user_id` bigint NOT NULL;",
+		"CREATE INDEX `idx_orders_user` ON `orders` (`user_id`);",
+		"ALTER TABLE `coupons` MODIFY COLUMN `code` varchar(20) NULL;",
+		"CREATE INDEX `idx_code` ON `coupons` (`code`);",
+		"ALTER TABLE `orders` ADD CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`);",
+	}, sqls)
+}
+
+func TestDefaultLiteral(t *testing.T) {
+	tests := []struct {
+		columnType string
+		value      string
+		expected   string
+	}{
+		{columnType: "int", value: "0", expected: "0"},
+		{columnType: "decimal(10,2)", value: "-1.50", expected: "-1.50"},
+		{columnType: "varchar(10)", value: "10", expected: "'10'"},
+		{columnType: "varchar(10)", value: `it's C:\`, expected: `'it''s C:\\'`},
+		{columnType: "datetime(3)", value: "CURRENT_TIMESTAMP(3)", expected: "CURRENT_TIMESTAMP(3)"},
+		{columnType: "json", value: "(json_array())", expected: "(json_array())"},
+		{columnType: "char(36)", value: "uuid()", expected: "(uuid())"},
+		{columnType: "json", value: "json_object(_utf8mb4'a',1)", expected: "(json_object(_utf8mb4'a',1))"},
+		{columnType: "bit(1)", value: "b'0'", expected: "b'0'"},
+		{columnType: "varbinary(4)", value: "0x0A0B", expected: "0x0A0B"},
+		{columnType: "varchar(10)", value: "b'0'", expected: `'b''0'''`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.columnType+" "+tt.value, func(t *testing.T) {
+			column := ColumnInfo{Type: tt.columnType, Default: sql.NullString{String: tt.value, Valid: true}}
+			assert.Equal(t, tt.expected, defaultLiteral(column))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// bytesPerToken is the rough number of bytes per token used to convert a token budget into bytes
+const bytesPerToken = 4
+
+// cursorPrefix versions the cursor format so that old cursors can be rejected when it changes
+const cursorPrefix = "v2:"
+
+// cursorState is the content of a cursor. The filter is kept in the cursor, so that a continuation
+// called with only the cursor returns the next page of the same tables
+type cursorState struct {
+	After      string `json:"after"`
+	Prefix     string `json:"prefix,omitempty"`
+	Pattern    string `json:"pattern,omitempty"`
+	HasComment *bool  `json:"hasComment,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+// encodeCursor creates an opaque continuation cursor that resumes after filter.After with the same filter
+func encodeCursor(filter TableFilter) string {
+	data, _ := json.Marshal(cursorState{
+		After:      filter.After,
+		Prefix:     filter.Prefix,
+		Pattern:    filter.Pattern,
+		HasComment: filter.HasComment,
+		Limit:      filter.Limit,
+	})
+	return base64.RawURLEncoding.EncodeToString(append([]byte(cursorPrefix), data...))
+}
+
+// decodeCursor returns the filter of a continuation cursor, whose After is the table after which it resumes
+func decodeCursor(cursor string) (TableFilter, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) {
+		return TableFilter{}, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	var state cursorState
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(string(data), cursorPrefix)), &state); err != nil || state.After == "" {
+		return TableFilter{}, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	return TableFilter{
+		After:      state.After,
+		Prefix:     state.Prefix,
+		Pattern:    state.Pattern,
+		HasComment: state.HasComment,
+		Limit:      state.Limit,
+	}, nil
+}
+
+// fitTables finds the largest number of tables whose rendered output fits in maxBytes.
+// render is called with the number of tables to include. At least one table is always
+// included so that a continuation always makes progress, even if it exceeds the limit.
+func fitTables(count int, maxBytes int, render func(n int) (string, error)) (string, error) {
+	full, err := render(count)
+	if err != nil || maxBytes <= 0 || len(full) <= maxBytes || count <= 1 {
+		return full, err
+	}
+
+	// Binary search the largest n in [1, count) that fits
+	best := 1
+	low, high := 2, count-1
+	for low <= high {
+		mid := (low + high) / 2
+		output, err := render(mid)
+		if err != nil {
+			return "", err
+		}
+		if len(output) <= maxBytes {
+			best = mid
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return render(best)
+}
+
+// countAfter counts the items whose name comes after the given name, which is every item when it is empty
+func countAfter[T any](items []T, name func(T) string, after string) int {
+	count := 0
+	for _, item := range items {
+		if name(item) > after {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	hasComment := true
+	filter := TableFilter{After: "order_items", Prefix: "order", Pattern: "%s", HasComment: &hasComment, Limit: 20}
+	decoded, err := decodeCursor(encodeCursor(filter))
+	require.NoError(t, err)
+	assert.Equal(t, filter, decoded)
+
+	_, err = decodeCursor("not a cursor")
+	assert.Error(t, err)
+	_, err = decodeCursor("b3JkZXJz") // base64 of "orders" without the prefix
+	assert.Error(t, err)
+	_, err = decodeCursor("YWZ0ZXI6b3JkZXJz") // base64 of "after:orders", the format without the filter
+	assert.Error(t, err)
+}
+
+func TestGetTableFilter_Cursor(t *testing.T) {
+	hasComment := true
+	cursor := encodeCursor(TableFilter{After: "orders", Prefix: "order", HasComment: &hasComment, Limit: 20})
+	getTableFilter := func(arguments map[string]interface{}) (TableFilter, error) {
+		return getTableFilter(mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}})
+	}
+
+	filter, err := getTableFilter(map[string]interface{}{"cursor": cursor})
+	require.NoError(t, err)
+	assert.Equal(t, TableFilter{After: "orders", Prefix: "order", HasComment: &hasComment, Limit: 20}, filter, "the cursor alone keeps the filter")
+
+	filter, err = getTableFilter(map[string]interface{}{"cursor": cursor, "prefix": "order", "hasComment": true, "limit": float64(50)})
+	require.NoError(t, err)
+	assert.Equal(t, TableFilter{After: "orders", Prefix: "order", HasComment: &hasComment, Limit: 50}, filter, "the filter may be repeated and the limit changed")
+
+	_, err = getTableFilter(map[string]interface{}{"cursor": cursor, "prefix": "user"})
+	assert.ErrorContains(t, err, "different filter")
+	_, err = getTableFilter(map[string]interface{}{"cursor": cursor, "hasComment": false})
+	assert.ErrorContains(t, err, "different filter")
+}
+
+func TestFitTables(t *testing.T) {
+	// Each table is 10 bytes and the footer for omitted tables is 5 bytes
+	render := func(n int) (string, error) {
+		output := strings.Repeat("x", n*10)
+		if n < 10 {
+			output += "-----"
+		}
+		return output, nil
+	}
+
+	tests := []struct {
+		maxBytes int
+		expected int
+	}{
+		{maxBytes: 0, expected: 100},
+		{maxBytes: 100, expected: 100},
+		{maxBytes: 99, expected: 95},
+		{maxBytes: 35, expected: 35},
+		{maxBytes: 34, expected: 25},
+		{maxBytes: 1, expected: 15}, // At least one table is always included
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("maxBytes=%d", tt.maxBytes), func(t *testing.T) {
+			output, err := fitTables(10, tt.maxBytes, render)
+			require.NoError(t, err)
+			assert.Len(t, output, tt.expected)
+		})
+	}
+}
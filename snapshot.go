@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SchemaSnapshot is a complete description of a database schema, as written by the dump subcommand in json format
+type SchemaSnapshot struct {
+	Database         string                       `json:"database"`
+	Tables           []TableDetail                `json:"tables"`
+	GeneratedColumns map[string][]GeneratedColumn `json:"generatedColumns"` // Keyed by table name
+	Views            []SchemaObject               `json:"views"`
+	Triggers         []SchemaObject               `json:"triggers"`
+	Routines         []SchemaObject               `json:"routines"`
+}
+
+// LoadSnapshots loads the snapshots written by `dump --format json` or the DDL files (.sql) parsed by ParseDDLFile
+// from a file, or from every .json and .sql file in a directory
+func LoadSnapshots(path string) ([]SchemaSnapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		jsonFiles, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sqlFiles, err := filepath.Glob(filepath.Join(path, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(jsonFiles, sqlFiles...)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no snapshot files (*.json, *.sql) in %s", path)
+		}
+	}
+
+	var snapshots []SchemaSnapshot
+	for _, file := range files {
+		if strings.EqualFold(filepath.Ext(file), ".sql") {
+			parsed, err := ParseDDLFile(file)
+			if err != nil {
+				return nil, err
+			}
+			snapshots = append(snapshots, parsed...)
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var snapshot SchemaSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if snapshot.Database == "" {
+			return nil, fmt.Errorf("%s: database is not set", file)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// snapshotDB serves schema information from snapshots instead of a live connection
+type snapshotDB struct {
+	databases map[string]*SchemaSnapshot
+}
+
+// NewSnapshotDB creates a DB that serves the schema of the snapshots, one per database
+func NewSnapshotDB(snapshots []SchemaSnapshot) (DB, error) {
+	databases := make(map[string]*SchemaSnapshot, len(snapshots))
+	for i := range snapshots {
+		snapshot := &snapshots[i]
+		if _, ok := databases[snapshot.Database]; ok {
+			return nil, fmt.Errorf("database %s is in more than one snapshot", snapshot.Database)
+		}
+		sort.Slice(snapshot.Tables, func(a, b int) bool { return snapshot.Tables[a].Name < snapshot.Tables[b].Name })
+		databases[snapshot.Database] = snapshot
+	}
+	return &snapshotDB{databases: databases}, nil
+}
+
+// database returns the snapshot of the database
+func (db *snapshotDB) database(dbName string) (*SchemaSnapshot, error) {
+	snapshot, ok := db.databases[dbName]
+	if !ok {
+		names := make([]string, 0, len(db.databases))
+		for name := range db.databases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("database %s is not in the snapshot (available: %s)", dbName, strings.Join(names, ", "))
+	}
+	return snapshot, nil
+}
+
+// table returns the table of the snapshot, or nil if it does not exist
+func (db *snapshotDB) table(dbName string, tableName string) (*TableDetail, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshot.Tables {
+		if snapshot.Tables[i].Name == tableName {
+			return &snapshot.Tables[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// filterTables returns the tables matching the filter, applying After and Limit like the SQL query does
+func (db *snapshotDB) filterTables(dbName string, filter TableFilter) ([]*TableDetail, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := filter.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*TableDetail
+	for i := range snapshot.Tables {
+		t := &snapshot.Tables[i]
+		if filter.After != "" && t.Name <= filter.After || !match(t.Name, t.Comment) {
+			continue
+		}
+		if filter.Limit > 0 && len(tables) == filter.Limit {
+			break
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// matcher returns a function reporting whether a table matches the conditions of the filter, excluding After and Limit.
+// Names are compared case-insensitively like the default collation of INFORMATION_SCHEMA
+func (f TableFilter) matcher() (func(name string, comment string) bool, error) {
+	var pattern *regexp.Regexp
+	if f.Pattern != "" {
+		var err error
+		pattern, err = regexp.Compile("(?is)^" + likeToRegexp(f.Pattern) + "$")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(name string, comment string) bool {
+		if f.Prefix != "" && !strings.HasPrefix(strings.ToLower(name), strings.ToLower(f.Prefix)) {
+			return false
+		}
+		if pattern != nil && !pattern.MatchString(name) {
+			return false
+		}
+		if f.HasComment != nil && *f.HasComment != (comment != "") {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// likeToRegexp converts a LIKE pattern into a regular expression
+func likeToRegexp(pattern string) string {
+	var re strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			re.WriteString(".*")
+		case r == '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return re.String()
+}
+
+// summary converts a table of the snapshot into a summary with the declared foreign keys only,
+// since virtual and inferred relationships are added by the handler
+func (t *TableDetail) summary() TableSummary {
+	return TableSummary{
+		Name:    t.Name,
+		Comment: t.Comment,
+		PK:      t.PrimaryKeys,
+		UK:      t.UniqueKeys,
+		FK:      declaredForeignKeys(t.ForeignKeys),
+	}
+}
+
+// declaredForeignKeys returns the foreign keys that are declared in the database
+func declaredForeignKeys(fks []ForeignKey) []ForeignKey {
+	var declared []ForeignKey
+	for _, fk := range fks {
+		if fk.Origin == FKOriginDeclared {
+			declared = append(declared, fk)
+		}
+	}
+	return declared
+}
+
+func (db *snapshotDB) FetchAllTableSummaries(ctx context.Context, dbName string) ([]TableSummary, error) {
+	return db.FetchTableSummaries(ctx, dbName, TableFilter{})
+}
+
+func (db *snapshotDB) FetchTableSummaries(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	tables, err := db.filterTables(dbName, filter)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]TableSummary, 0, len(tables))
+	for _, t := range tables {
+		summaries = append(summaries, t.summary())
+	}
+	return summaries, nil
+}
+
+func (db *snapshotDB) FetchTableWithComments(ctx context.Context, dbName string, filter TableFilter) ([]TableSummary, error) {
+	tables, err := db.filterTables(dbName, filter)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]TableSummary, 0, len(tables))
+	for _, t := range tables {
+		summaries = append(summaries, TableSummary{Name: t.Name, Comment: t.Comment})
+	}
+	return summaries, nil
+}
+
+func (db *snapshotDB) CountTables(ctx context.Context, dbName string, filter TableFilter) (int, int, error) {
+	all := filter
+	all.After, all.Limit = "", 0
+	tables, err := db.filterTables(dbName, all)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(tables), countAfter(tables, func(t *TableDetail) string { return t.Name }, filter.After), nil
+}
+
+func (db *snapshotDB) FetchPrimaryKeys(ctx context.Context, dbName string, tableName string) ([]string, error) {
+	t, err := db.table(dbName, tableName)
+	if t == nil {
+		return nil, err
+	}
+	return t.PrimaryKeys, nil
+}
+
+func (db *snapshotDB) FetchUniqueKeys(ctx context.Context, dbName string, tableName string) ([]UniqueKey, error) {
+	t, err := db.table(dbName, tableName)
+	if t == nil {
+		return nil, err
+	}
+	return t.UniqueKeys, nil
+}
+
+func (db *snapshotDB) FetchForeignKeys(ctx context.Context, dbName string, tableName string) ([]ForeignKey, error) {
+	t, err := db.table(dbName, tableName)
+	if t == nil {
+		return nil, err
+	}
+	return declaredForeignKeys(t.ForeignKeys), nil
+}
+
+func (db *snapshotDB) FetchTableColumns(ctx context.Context, dbName string, tableName string) ([]ColumnInfo, error) {
+	t, err := db.table(dbName, tableName)
+	if t == nil {
+		return nil, err
+	}
+	return t.Columns, nil
+}
+
+func (db *snapshotDB) FetchTableIndexes(ctx context.Context, dbName string, tableName string) ([]IndexInfo, error) {
+	t, err := db.table(dbName, tableName)
+	if t == nil {
+		return nil, err
+	}
+	return t.Indexes, nil
+}
+
+func (db *snapshotDB) FetchAllColumns(ctx context.Context, dbName string) (map[string][]ColumnInfo, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string][]ColumnInfo, len(snapshot.Tables))
+	for _, t := range snapshot.Tables {
+		columns[t.Name] = t.Columns
+	}
+	return columns, nil
+}
+
+func (db *snapshotDB) FetchColumns(ctx context.Context, dbName string, tableNames []string) (map[string][]ColumnInfo, error) {
+	columns := make(map[string][]ColumnInfo, len(tableNames))
+	for _, name := range tableNames {
+		t, err := db.table(dbName, name)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			columns[t.Name] = t.Columns
+		}
+	}
+	return columns, nil
+}
+
+// FetchIndexedColumns returns the first columns of the keys and indexes. Foreign key columns are included
+// because InnoDB always creates an index for them, which the snapshot doesn't list separately
+func (db *snapshotDB) FetchIndexedColumns(ctx context.Context, dbName string) (map[string]map[string]bool, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := make(map[string]map[string]bool)
+	for _, t := range snapshot.Tables {
+		for _, idx := range t.allIndexes() {
+			if indexed[t.Name] == nil {
+				indexed[t.Name] = make(map[string]bool)
+			}
+			indexed[t.Name][idx.Columns[0]] = true
+		}
+		for _, fk := range declaredForeignKeys(t.ForeignKeys) {
+			if indexed[t.Name] == nil {
+				indexed[t.Name] = make(map[string]bool)
+			}
+			indexed[t.Name][fk.Columns[0]] = true
+		}
+	}
+	return indexed, nil
+}
+
+func (db *snapshotDB) FetchColumnIndexes(ctx context.Context, dbName string, tableName string, columnName string) ([]IndexInfo, error) {
+	t, err := db.table(dbName, tableName)
+	if t == nil {
+		return nil, err
+	}
+
+	var indexes []IndexInfo
+	for _, idx := range t.allIndexes() {
+		if containsFold(idx.Columns, columnName) {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes, nil
+}
+
+// allIndexes returns the primary key, unique keys and other indexes of the table, PRIMARY first and then by name
+func (t *TableDetail) allIndexes() []IndexInfo {
+	var indexes []IndexInfo
+	for _, uk := range t.UniqueKeys {
+		indexes = append(indexes, IndexInfo{Name: uk.Name, Columns: uk.Columns, Unique: true})
+	}
+	indexes = append(indexes, t.Indexes...)
+	sort.SliceStable(indexes, func(a, b int) bool { return indexes[a].Name < indexes[b].Name })
+
+	if len(t.PrimaryKeys) > 0 {
+		indexes = append([]IndexInfo{{Name: "PRIMARY", Columns: t.PrimaryKeys, Unique: true}}, indexes...)
+	}
+	return indexes
+}
+
+// FetchReferencingForeignKeys returns the declared foreign keys referencing the table. Foreign keys to
+// another database can't be found, because the snapshot doesn't record the database of referenced tables
+func (db *snapshotDB) FetchReferencingForeignKeys(ctx context.Context, dbName string, tableName string) ([]DependencyEdge, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []DependencyEdge
+	for _, t := range snapshot.Tables {
+		for _, fk := range declaredForeignKeys(t.ForeignKeys) {
+			if fk.RefTable == tableName {
+				edges = append(edges, DependencyEdge{Table: t.Name, FK: fk})
+			}
+		}
+	}
+	return edges, nil
+}
+
+func (db *snapshotDB) FetchGeneratedColumns(ctx context.Context, dbName string, tableName string) ([]GeneratedColumn, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.GeneratedColumns[tableName], nil
+}
+
+func (db *snapshotDB) FetchViews(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Views, nil
+}
+
+func (db *snapshotDB) FetchTriggers(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Triggers, nil
+}
+
+func (db *snapshotDB) FetchRoutines(ctx context.Context, dbName string) ([]SchemaObject, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Routines, nil
+}
+
+func (db *snapshotDB) FetchObjectTypes(ctx context.Context, dbName string, name string) ([]string, error) {
+	snapshot, err := db.database(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []string
+	isView := slices.ContainsFunc(snapshot.Views, func(v SchemaObject) bool { return v.Name == name })
+	if isView {
+		types = append(types, "VIEW")
+	} else if slices.ContainsFunc(snapshot.Tables, func(t TableDetail) bool { return t.Name == name }) {
+		types = append(types, "TABLE")
+	}
+	for _, objects := range [][]SchemaObject{snapshot.Routines, snapshot.Triggers} {
+		for _, o := range objects {
+			if o.Name == name {
+				types = append(types, o.Type)
+			}
+		}
+	}
+	return types, nil
+}
+
+// FetchCreateStatement is not supported because snapshots don't contain the DDL
+func (db *snapshotDB) FetchCreateStatement(ctx context.Context, dbName string, objectType string, name string) (string, error) {
+	return "", fmt.Errorf("SHOW CREATE is not available when serving a schema snapshot")
+}
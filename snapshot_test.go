@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSnapshotDB(t *testing.T) DB {
+	t.Helper()
+	snapshots, err := LoadSnapshots("testdata/snapshots")
+	require.NoError(t, err)
+	db, err := NewSnapshotDB(snapshots)
+	require.NoError(t, err)
+	return db
+}
+
+// columnsRecordingDB records the column queries of the handler
+type columnsRecordingDB struct {
+	DB
+	requested  [][]string
+	allColumns int
+}
+
+func (db *columnsRecordingDB) FetchColumns(ctx context.Context, dbName string, tableNames []string) (map[string][]ColumnInfo, error) {
+	db.requested = append(db.requested, tableNames)
+	return db.DB.FetchColumns(ctx, dbName, tableNames)
+}
+
+func (db *columnsRecordingDB) FetchAllColumns(ctx context.Context, dbName string) (map[string][]ColumnInfo, error) {
+	db.allColumns++
+	return db.DB.FetchAllColumns(ctx, dbName)
+}
+
+// failingIndexesDB fails to get the indexes
+type failingIndexesDB struct {
+	DB
+}
+
+func (db failingIndexesDB) FetchTableIndexes(ctx context.Context, dbName string, tableName string) ([]IndexInfo, error) {
+	return nil, errors.New("connection lost")
+}
+
+func TestSnapshotDB(t *testing.T) {
+	db := newTestSnapshotDB(t)
+	ctx := context.Background()
+
+	t.Run("tables are sorted and only declared foreign keys are returned", func(t *testing.T) {
+		tables, err := db.FetchAllTableSummaries(ctx, "ecshop")
+		require.NoError(t, err)
+		require.Len(t, tables, 3)
+		assert.Equal(t, "coupons", tables[0].Name)
+		assert.Equal(t, "orders", tables[1].Name)
+		assert.Equal(t, []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}, tables[1].FK)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		hasComment := true
+		tables, err := db.FetchTableWithComments(ctx, "ecshop", TableFilter{Pattern: "%S", HasComment: &hasComment})
+		require.NoError(t, err)
+		assert.Equal(t, []TableSummary{{Name: "orders", Comment: "Order header"}, {Name: "users", Comment: "User information"}}, tables)
+
+		tables, err = db.FetchTableWithComments(ctx, "ecshop", TableFilter{After: "coupons", Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []TableSummary{{Name: "orders", Comment: "Order header"}}, tables)
+
+		total, after, err := db.CountTables(ctx, "ecshop", TableFilter{Prefix: "U"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, 1, after, "every table when After is empty")
+
+		total, after, err = db.CountTables(ctx, "ecshop", TableFilter{After: "coupons", Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 3, total, "After and Limit don't limit the total")
+		assert.Equal(t, 2, after)
+	})
+
+	t.Run("columns keep nullability and defaults", func(t *testing.T) {
+		columns, err := db.FetchTableColumns(ctx, "ecshop", "users")
+		require.NoError(t, err)
+		require.Len(t, columns, 3)
+		assert.Equal(t, "NO", columns[0].IsNullable)
+		assert.False(t, columns[0].Default.Valid)
+		assert.Equal(t, "YES", columns[2].IsNullable)
+		assert.Equal(t, "active", columns[2].Default.String)
+	})
+
+	t.Run("columns of the tables", func(t *testing.T) {
+		columns, err := db.FetchColumns(ctx, "ecshop", []string{"users", "missing"})
+		require.NoError(t, err)
+		assert.Len(t, columns, 1, "missing tables are skipped")
+		assert.Len(t, columns["users"], 3)
+	})
+
+	t.Run("column indexes include PRIMARY and unique keys", func(t *testing.T) {
+		indexes, err := db.FetchColumnIndexes(ctx, "ecshop", "users", "id")
+		require.NoError(t, err)
+		assert.Equal(t, []IndexInfo{{Name: "PRIMARY", Columns: []string{"id"}, Unique: true}}, indexes)
+
+		indexed, err := db.FetchIndexedColumns(ctx, "ecshop")
+		require.NoError(t, err)
+		assert.True(t, indexed["orders"]["user_id"])
+		assert.False(t, indexed["orders"]["coupon_id"])
+	})
+
+	t.Run("object types", func(t *testing.T) {
+		types, err := db.FetchObjectTypes(ctx, "ecshop", "order_prices")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"VIEW"}, types)
+	})
+
+	t.Run("unknown database", func(t *testing.T) {
+		_, err := db.FetchAllTableSummaries(ctx, "crm")
+		assert.EqualError(t, err, "database crm is not in the snapshot (available: ecshop)")
+	})
+}
+
+func TestLoadSnapshots_Errors(t *testing.T) {
+	_, err := LoadSnapshots("testdata/missing.json")
+	assert.Error(t, err)
+
+	_, err = LoadSnapshots("testdata/templates")
+	assert.ErrorContains(t, err, "no snapshot files")
+
+	_, err = NewSnapshotDB([]SchemaSnapshot{{Database: "ecshop"}, {Database: "ecshop"}})
+	assert.EqualError(t, err, "database ecshop is in more than one snapshot")
+}
+
+func TestHandler_Snapshot(t *testing.T) {
+	handler := NewHandler(newTestSnapshotDB(t), "ecshop")
+
+	t.Run("list_tables", func(t *testing.T) {
+		result, err := handler.ListTables(t.Context(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		assert.Equal(t, `Tables in database "ecshop" (Total: 3)
+Format: Table Name - Table Comment [PK: Primary Key] [UK: Unique Key 1; Unique Key 2...] [FK: Foreign Key -> Referenced Table.Column; ...]
+* Composite keys (keys composed of multiple columns) are grouped in parentheses: (col1, col2)
+* Multiple different key constraints are separated by semicolons: key1; key2
+
+- coupons -  [PK: id]
+- orders - Order header [PK: id] [FK: user_id -> users.id]
+- users - User information [PK: id] [UK: email]
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("list_tables with full detail fetches only the columns of the page", func(t *testing.T) {
+		db := &columnsRecordingDB{DB: newTestSnapshotDB(t)}
+		result, err := NewHandler(db, "ecshop").ListTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{"detail": "full", "limit": float64(1)}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "- id: int NOT NULL")
+		assert.Equal(t, [][]string{{"coupons"}}, db.requested)
+		assert.Zero(t, db.allColumns, "the columns of all tables are not fetched")
+	})
+
+	t.Run("describe_tables", func(t *testing.T) {
+		result, err := handler.DescribeTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"tableNames": []interface{}{"users"},
+			}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "result should not be an error: %v", result.Content)
+
+		assert.Equal(t, `# Table: users - User information
+
+## Columns
+- id: int NOT NULL [User system ID]
+- email: varchar(255) NOT NULL [Email address]
+- status: varchar(10) NULL DEFAULT active [Status]
+
+## Key Information
+[PK: id]
+[UK: email]
+[INDEX: status]
+`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("describe_tables error", func(t *testing.T) {
+		handler := NewHandler(failingIndexesDB{DB: newTestSnapshotDB(t)}, "ecshop")
+		result, err := handler.DescribeTables(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{"tableNames": []interface{}{"users"}}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "Failed to describe table users: failed to get index information: connection lost", result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("show_create is not available", func(t *testing.T) {
+		result, err := handler.ShowCreate(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+				"objectNames": []interface{}{"users"},
+			}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Templates holds the parsed output templates of the tools
+type Templates struct {
+	ListTables      *template.Template
+	DescribeTable   *template.Template
+	DependencyOrder *template.Template
+	ColumnImpact    *template.Template
+	ShowCreate      *template.Template
+	SchemaDiff      *template.Template
+	ListConnections *template.Template
+}
+
+// templateDefinition describes an overridable template: the file name in the template directory,
+// the built-in text and sample data used to validate an override
+type templateDefinition struct {
+	file    string
+	builtin string
+	sample  any
+	target  func(*Templates) **template.Template
+}
+
+var templateDefinitions = []templateDefinition{
+	{
+		file:    "list_tables.tmpl",
+		builtin: listTablesTemplate,
+		sample: ListTablesData{DBName: "sample", Detail: detailFull, Total: 1, Returned: 1, Tables: []TableSummary{{
+			Name: "orders", Comment: "Orders", PK: []string{"id"},
+			Columns: []ColumnInfo{{Name: "id", Type: "int", IsNullable: "NO", Comment: "ID"}},
+			UK:      []UniqueKey{{Name: "uk_code", Columns: []string{"code"}}},
+			FK:      []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}},
+		}}},
+		target: func(t *Templates) **template.Template { return &t.ListTables },
+	},
+	{
+		file:    "describe_tables.tmpl",
+		builtin: describeTableDetailTemplate,
+		sample: TableDetail{
+			Name: "orders", Comment: "Orders",
+			Columns:     []ColumnInfo{{Name: "id", Type: "int", IsNullable: "NO", Default: sql.NullString{String: "0", Valid: true}, Comment: "ID"}},
+			PrimaryKeys: []string{"id"},
+			UniqueKeys:  []UniqueKey{{Name: "uk_code", Columns: []string{"code"}}},
+			ForeignKeys: []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}},
+			Indexes:     []IndexInfo{{Name: "idx_created_at", Columns: []string{"created_at"}}},
+		},
+		target: func(t *Templates) **template.Template { return &t.DescribeTable },
+	},
+	{
+		file:    "table_dependency_order.tmpl",
+		builtin: dependencyOrderTemplate,
+		sample: DependencyOrderData{DBName: "sample", DependencyOrder: BuildDependencyOrder([]TableSummary{
+			{Name: "orders", FK: []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}},
+			{Name: "users", FK: []ForeignKey{{Name: "fk_parent", Columns: []string{"parent_id"}, RefTable: "users", RefColumns: []string{"id"}}}},
+		})},
+		target: func(t *Templates) **template.Template { return &t.DependencyOrder },
+	},
+	{
+		file:    "analyze_column_impact.tmpl",
+		builtin: columnImpactTemplate,
+		sample: ColumnImpact{
+			Table: "orders", Column: "user_id",
+			Indexes:  []IndexInfo{{Name: "idx_user_id", Columns: []string{"user_id"}}},
+			Triggers: []SchemaObject{{Name: "trg_orders", Type: "TRIGGER", Table: "orders", Timing: "BEFORE", Event: "INSERT"}},
+			Routines: []SchemaObject{{Name: "count_orders", Type: "FUNCTION"}},
+		},
+		target: func(t *Templates) **template.Template { return &t.ColumnImpact },
+	},
+	{
+		file:    "show_create.tmpl",
+		builtin: showCreateTemplate,
+		sample: ShowCreateData{
+			DBName: "sample",
+			Objects: []CreateStatement{
+				{Name: "orders", Type: "TABLE", Statement: "CREATE TABLE `orders` (\n  `id` int NOT NULL\n) ENGINE=InnoDB"},
+				{Name: "count_orders", Type: "FUNCTION", Statement: "CREATE FUNCTION `count_orders`() RETURNS int\nRETURN 1"},
+			},
+			NotFound: []string{"missing"},
+		},
+		target: func(t *Templates) **template.Template { return &t.ShowCreate },
+	},
+	{
+		file:    "diff_schemas.tmpl",
+		builtin: schemaDiffTemplate,
+		sample: DiffSchemas(
+			SchemaSnapshot{Database: "staging", Tables: []TableDetail{{
+				Name: "orders", Comment: "Orders",
+				Columns:     []ColumnInfo{{Name: "id", Type: "bigint", IsNullable: "NO"}, {Name: "code", Type: "varchar(20)", IsNullable: "NO"}},
+				PrimaryKeys: []string{"id"},
+				UniqueKeys:  []UniqueKey{{Name: "uk_code", Columns: []string{"code"}}},
+				ForeignKeys: []ForeignKey{{Name: "fk_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}},
+				Indexes:     []IndexInfo{{Name: "idx_created_at", Columns: []string{"created_at"}}},
+			}, {Name: "users"}}},
+			SchemaSnapshot{Database: "production", Tables: []TableDetail{{
+				Name:    "orders",
+				Columns: []ColumnInfo{{Name: "id", Type: "int", IsNullable: "NO"}, {Name: "note", Type: "text", IsNullable: "YES"}},
+			}, {Name: "logs"}}},
+		),
+		target: func(t *Templates) **template.Template { return &t.SchemaDiff },
+	},
+	{
+		file:    "list_connections.tmpl",
+		builtin: listConnectionsTemplate,
+		sample: ListConnectionsData{Connections: []ConnectionInfo{
+			{Name: "primary", Description: "Primary MySQL", Database: "app", Default: true},
+			{Name: "analytics"},
+		}},
+		target: func(t *Templates) **template.Template { return &t.ListConnections },
+	},
+}
+
+// NewTemplates parses the built-in templates, overridden by the files in dir when it is not empty.
+// Overrides are validated by executing them with sample data so that mistakes are reported at startup.
+func NewTemplates(dir string) (*Templates, error) {
+	if dir != "" {
+		if err := checkTemplateDir(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	templates := &Templates{}
+	for _, def := range templateDefinitions {
+		text, source := def.builtin, "built-in "+def.file
+		if dir != "" {
+			path := filepath.Join(dir, def.file)
+			data, err := os.ReadFile(path)
+			switch {
+			case err == nil:
+				text, source = string(data), path
+			case !errors.Is(err, os.ErrNotExist):
+				return nil, err
+			}
+		}
+
+		tmpl, err := template.New(def.file).Funcs(funcMap).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", source, err)
+		}
+		if err := tmpl.Execute(io.Discard, def.sample); err != nil {
+			return nil, fmt.Errorf("failed to execute template %s with sample data: %w", source, err)
+		}
+
+		*def.target(templates) = tmpl
+	}
+
+	return templates, nil
+}
+
+// checkTemplateDir reports files that look like templates but don't override anything, which are usually typos
+func checkTemplateDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read template directory: %w", err)
+	}
+
+	var known []string
+	for _, def := range templateDefinitions {
+		known = append(known, def.file)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".tmpl" {
+			continue
+		}
+		isKnown := false
+		for _, name := range known {
+			if e.Name() == name {
+				isKnown = true
+				break
+			}
+		}
+		if !isKnown {
+			return fmt.Errorf("unknown template %s in %s (available: %s)", e.Name(), dir, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
+
+// builtinTemplates returns the built-in templates, which are always valid
+func builtinTemplates() *Templates {
+	templates, err := NewTemplates("")
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
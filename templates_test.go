@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTemplates_Override(t *testing.T) {
+	templates, err := NewTemplates("testdata/templates")
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	err = templates.ListTables.Execute(&output, ListTablesData{
+		DBName: "ecshop",
+		Tables: []TableSummary{
+			{Name: "orders", Comment: "Order header information"},
+			{Name: "users"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ecshop:\nORDERS # Order h...\nUSERS\n", output.String())
+
+	// Templates that are not overridden are the built-in ones
+	builtin := builtinTemplates()
+	assert.Equal(t, builtin.DescribeTable.Root.String(), templates.DescribeTable.Root.String())
+}
+
+func TestNewTemplates_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		errorMsg string
+	}{
+		{
+			name:     "parse error",
+			file:     "list_tables.tmpl",
+			content:  "{{range .Tables}}",
+			errorMsg: "failed to parse template",
+		},
+		{
+			name:     "unknown field",
+			file:     "describe_tables.tmpl",
+			content:  "{{.NoSuchField}}",
+			errorMsg: "failed to execute template",
+		},
+		{
+			name:     "unknown file",
+			file:     "list_table.tmpl",
+			content:  "{{.DBName}}",
+			errorMsg: "unknown template list_table.tmpl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0o644))
+
+			_, err := NewTemplates(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+			assert.Contains(t, err.Error(), tt.file)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+-- users table
+CREATE TABLE users (
+    id INT AUTO_INCREMENT PRIMARY KEY COMMENT 'User ID',
+    email VARCHAR(255) NOT NULL COMMENT 'Email address',
+    UNIQUE KEY uk_email (email)
+) COMMENT='User information';
+
+-- orders table
+CREATE TABLE orders (
+    id INT AUTO_INCREMENT PRIMARY KEY COMMENT 'Order ID',
+    user_id INT NOT NULL COMMENT 'User ID (FK)',
+    price INT NOT NULL COMMENT 'Price',
+    price_with_tax INT AS (price * 110 / 100) COMMENT 'Price including tax',
+    order_date DATETIME COMMENT 'Order date',
+    INDEX idx_user_date (user_id, order_date),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)
+) COMMENT='Order header';
+
+-- view that references orders.price
+CREATE VIEW order_prices AS SELECT id, price FROM orders;
+
+-- trigger that references orders.price
+CREATE TRIGGER trg_orders_price BEFORE INSERT ON orders FOR EACH ROW SET NEW.price = GREATEST(NEW.price, 0);
+
+-- function that references orders.user_id
+CREATE FUNCTION count_user_orders(uid INT) RETURNS INT DETERMINISTIC READS SQL DATA RETURN (SELECT COUNT(*) FROM orders WHERE user_id = uid);
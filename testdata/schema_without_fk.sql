@@ -0,0 +1,30 @@
+-- Legacy schema without any foreign key constraints
+
+-- users table
+CREATE TABLE users (
+    id INT AUTO_INCREMENT PRIMARY KEY COMMENT 'User ID',
+    name VARCHAR(255) NOT NULL COMMENT 'User name'
+) COMMENT='User information';
+
+-- shops table
+CREATE TABLE shops (
+    id INT AUTO_INCREMENT PRIMARY KEY COMMENT 'Shop ID',
+    code VARCHAR(50) NOT NULL COMMENT 'Shop code',
+    UNIQUE KEY uk_code (code)
+) COMMENT='Shop master';
+
+-- categories table
+CREATE TABLE categories (
+    category_code VARCHAR(50) PRIMARY KEY COMMENT 'Category code',
+    name VARCHAR(255) NOT NULL COMMENT 'Category name'
+) COMMENT='Category master';
+
+-- orders table
+CREATE TABLE orders (
+    id INT AUTO_INCREMENT PRIMARY KEY COMMENT 'Order ID',
+    user_id INT NOT NULL COMMENT 'User ID',
+    shop_code VARCHAR(50) NOT NULL COMMENT 'Shop code',
+    category_code VARCHAR(50) NOT NULL COMMENT 'Category code',
+    note_id VARCHAR(50) COMMENT 'Not a reference',
+    INDEX idx_user_id (user_id)
+) COMMENT='Order header';
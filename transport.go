@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	transportStdio = "stdio"
+	transportHTTP  = "http"
+
+	defaultListenAddress = "localhost:8080"
+	defaultBasePath      = "/mcp"
+
+	// shutdownTimeout is how long in-flight requests may take to finish after SIGTERM
+	shutdownTimeout = 10 * time.Second
+)
+
+// newHTTPHandler serves the MCP server over streamable HTTP at basePath,
+// and over SSE for older clients at basePath/sse and basePath/message.
+// Every request must be authenticated when auth is not nil, and requests from web pages of other origins are rejected
+func newHTTPHandler(s *server.MCPServer, srv *http.Server, basePath string, auth *authenticator) (http.Handler, *server.SSEServer) {
+	basePath = path.Clean("/" + basePath)
+
+	sse := server.NewSSEServer(s, server.WithStaticBasePath(basePath), server.WithHTTPServer(srv))
+
+	mux := http.NewServeMux()
+	mux.Handle(basePath, server.NewStreamableHTTPServer(s, server.WithEndpointPath(basePath)))
+	mux.Handle(sse.CompleteSsePath(), sse)
+	mux.Handle(sse.CompleteMessagePath(), sse)
+	var handler http.Handler = mux
+	if auth != nil {
+		handler = auth.middleware(handler)
+	}
+	return originMiddleware(handler, isLoopbackAddress(srv.Addr)), sse
+}
+
+// originMiddleware rejects requests sent by web pages of other origins, which browsers let any page send.
+// On a loopback address, which may be served without authentication, requests whose Host is not local are
+// rejected as well, because DNS rebinding lets a remote page reach the server under its own host name
+func originMiddleware(next http.Handler, loopback bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loopback && !isLocalHost(r.Host) {
+			log.Printf("Rejected a request for host %s from %s", r.Host, r.RemoteAddr)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				log.Printf("Rejected a request from origin %s from %s", origin, r.RemoteAddr)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLocalHost reports whether the Host header of a request names the local machine
+func isLocalHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+	}
+	return isLoopbackAddress(net.JoinHostPort(host, "0"))
+}
+
+// serveHTTP serves the MCP server on addr until ctx is canceled, then shuts down gracefully.
+// It serves HTTPS when tlsConfig is not nil
+func serveHTTP(ctx context.Context, s *server.MCPServer, addr, basePath string, auth *authenticator, tlsConfig *tls.Config) error {
+	srv := &http.Server{Addr: addr, ReadHeaderTimeout: 10 * time.Second, TLSConfig: tlsConfig}
+	handler, sse := newHTTPHandler(s, srv, basePath, auth)
+	srv.Handler = handler
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Serving MCP over HTTP on %s (streamable HTTP: %s, SSE: %s)", addr, path.Clean("/"+basePath), sse.CompleteSsePath())
+		if tlsConfig != nil {
+			// The certificates are already in TLSConfig
+			errCh <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down the HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// Closing the SSE sessions first lets their long-lived streams end before the server waits for them
+	if err := sse.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
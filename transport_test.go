@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMCPServerHandler(t *testing.T) *Handler {
+	t.Helper()
+	snapshots, err := LoadSnapshots("testdata/schema.sql")
+	require.NoError(t, err)
+	db, err := NewSnapshotDB(snapshots)
+	require.NoError(t, err)
+	return NewHandler(db, "")
+}
+
+func TestNewHTTPHandler(t *testing.T) {
+	s := newMCPServer(NewConnections(Connection{Name: defaultConnectionName, Handler: newTestMCPServerHandler(t)}))
+	handler, _ := newHTTPHandler(s, &http.Server{}, "team/mcp/", nil)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	t.Run("streamable HTTP", func(t *testing.T) {
+		body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+		resp, err := http.Post(ts.URL+"/team/mcp", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Mcp-Session-Id"))
+	})
+
+	t.Run("SSE", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/team/mcp/sse", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		reader := bufio.NewReader(resp.Body)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "event: endpoint\n", line)
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(line, "data: /team/mcp/message?sessionId="), line)
+	})
+
+	t.Run("other paths", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestNewHTTPHandler_Origin(t *testing.T) {
+	s := newMCPServer(NewConnections(Connection{Name: defaultConnectionName, Handler: newTestMCPServerHandler(t)}))
+	handler, _ := newHTTPHandler(s, &http.Server{Addr: "localhost:8080"}, "/mcp", nil)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+	post := func(t *testing.T, path string, header http.Header, host string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(initialize))
+		require.NoError(t, err)
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		if host != "" {
+			req.Host = host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, path := range []string{"/mcp", "/mcp/message", "/mcp/sse"} {
+		assert.Equal(t, http.StatusForbidden, post(t, path, http.Header{"Origin": {"https://evil.example"}}, ""), path)
+	}
+	assert.Equal(t, http.StatusForbidden, post(t, "/mcp", http.Header{"Origin": {"null"}}, ""))
+	assert.Equal(t, http.StatusForbidden, post(t, "/mcp", http.Header{}, "evil.example:8080"), "DNS rebinding sends the host name of the attacker")
+
+	assert.Equal(t, http.StatusOK, post(t, "/mcp", http.Header{}, ""), "clients other than browsers send no Origin")
+	assert.Equal(t, http.StatusOK, post(t, "/mcp", http.Header{"Origin": {ts.URL}}, ""), "same origin")
+	assert.Equal(t, http.StatusOK, post(t, "/mcp", http.Header{}, "localhost:8080"))
+}
+
+func TestIsLocalHost(t *testing.T) {
+	assert.True(t, isLocalHost("localhost:8080"))
+	assert.True(t, isLocalHost("localhost"))
+	assert.True(t, isLocalHost("127.0.0.1:8080"))
+	assert.True(t, isLocalHost("[::1]:8080"))
+	assert.True(t, isLocalHost("[::1]"))
+	assert.False(t, isLocalHost("evil.example:8080"))
+	assert.False(t, isLocalHost("10.0.0.5"))
+}
+
+func TestServeHTTP_GracefulShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveHTTP(ctx, newMCPServer(NewConnections(Connection{Name: defaultConnectionName, Handler: newTestMCPServerHandler(t)})), addr, "/mcp", nil, nil)
+	}()
+
+	// Keep an SSE stream open, which must not hold up the shutdown
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr + "/mcp/sse")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(shutdownTimeout):
+		t.Fatal("serveHTTP did not return after the context was canceled")
+	}
+}
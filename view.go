@@ -1,6 +1,8 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"text/template"
@@ -8,30 +10,60 @@ import (
 
 // ListTablesData is the data structure passed to the ListTables template
 type ListTablesData struct {
-	DBName string
-	Tables []TableSummary
+	DBName     string         `json:"database"`
+	Detail     string         `json:"detail"`   // Detail level: names, names+comments, keys or full
+	Total      int            `json:"total"`    // Number of tables matching the filter
+	Returned   int            `json:"returned"` // Number of tables in this response
+	Tables     []TableSummary `json:"tables"`
+	Omitted    int            `json:"omitted,omitempty"`    // Number of tables left after this response
+	NextCursor string         `json:"nextCursor,omitempty"` // Cursor to get the omitted tables
 }
 
 // listTablesTemplate is the output format for ListTables
-const listTablesTemplate = `Tables in database "{{.DBName}}" (Total: {{len .Tables}})
+const listTablesTemplate = `Tables in database "{{.DBName}}" (Total: {{.Total}}{{if ne .Total .Returned}}, Returned: {{.Returned}}{{end}})
+{{if eq .Detail "names" -}}
+{{range .Tables -}}
+- {{.Name}}
+{{end -}}
+{{else if eq .Detail "names+comments" -}}
+Format: Table Name - Table Comment
+
+{{range .Tables -}}
+- {{.Name}} - {{.Comment}}
+{{end -}}
+{{else -}}
 Format: Table Name - Table Comment [PK: Primary Key] [UK: Unique Key 1; Unique Key 2...] [FK: Foreign Key -> Referenced Table.Column; ...]
 * Composite keys (keys composed of multiple columns) are grouped in parentheses: (col1, col2)
 * Multiple different key constraints are separated by semicolons: key1; key2
-
+{{if eq .Detail "full"}}* Columns are listed under each table: - Column Name: Type NULL/NOT NULL [DEFAULT value] [Comment]
+{{end}}
 {{range .Tables -}}
 - {{.Name}} - {{.Comment}}{{if len .PK}} [PK: {{formatPK .PK}}]{{end}}{{if len .UK}} [UK: {{formatUK .UK}}]{{end}}{{if len .FK}} [FK: {{formatFK .FK}}]{{end}}
+{{range .Columns}}  {{formatColumn .}}
+{{end -}}
+{{end -}}
+{{end -}}
+{{if .NextCursor}}
+{{.Omitted}} more tables were omitted. Call list_tables with cursor "{{.NextCursor}}" to get them.
 {{end -}}
 `
 
 // TableDetail holds detailed information for individual tables (uses types from db.go)
 type TableDetail struct {
-	Name        string
-	Comment     string
-	Columns     []ColumnInfo
-	PrimaryKeys []string
-	UniqueKeys  []UniqueKey
-	ForeignKeys []ForeignKey
-	Indexes     []IndexInfo
+	Name        string       `json:"name"`
+	Comment     string       `json:"comment"`
+	Columns     []ColumnInfo `json:"columns"`
+	PrimaryKeys []string     `json:"primaryKey"`
+	UniqueKeys  []UniqueKey  `json:"uniqueKeys"`
+	ForeignKeys []ForeignKey `json:"foreignKeys"`
+	Indexes     []IndexInfo  `json:"indexes"`
+}
+
+// DescribeTablesData is the JSON output of describe_tables
+type DescribeTablesData struct {
+	DBName   string        `json:"database"`
+	Tables   []TableDetail `json:"tables"`
+	NotFound []string      `json:"notFound"` // Requested tables that do not exist
 }
 
 // describeTableDetailTemplate is the output format for describe_tables
@@ -47,12 +79,145 @@ const describeTableDetailTemplate = `# Table: {{.Name}}{{if .Comment}} - {{.Comm
 [INDEX: {{formatIndex .Indexes}}]{{end}}
 `
 
+// DependencyOrderData is the data structure passed to the TableDependencyOrder template
+type DependencyOrderData struct {
+	DBName string `json:"database"`
+	DependencyOrder
+}
+
+// dependencyOrderTemplate is the output format for table_dependency_order
+const dependencyOrderTemplate = `Table dependency order in database "{{.DBName}}" (Total: {{len .InsertionOrder}})
+* Tables marked [cycle N] are part of a foreign key cycle and cannot be fully ordered; see Cycles
+
+## Insertion Order (parents before children)
+{{range $i, $t := .InsertionOrder -}}
+{{inc $i}}. {{$t}}{{with $.CycleOf $t}} [cycle {{.}}]{{end}}
+{{end}}
+## Deletion Order (children before parents)
+{{range $i, $t := .DeletionOrder -}}
+{{inc $i}}. {{$t}}{{with $.CycleOf $t}} [cycle {{.}}]{{end}}
+{{end}}{{if .SelfReferences}}
+## Self-References
+{{range .SelfReferences -}}
+- {{formatDependencyEdge .}}
+{{end}}{{end}}{{if .Cycles}}
+## Cycles
+{{range $i, $c := .Cycles -}}
+- cycle {{inc $i}}: {{join $c.Tables ", "}}
+{{range $c.Edges}}  - {{formatDependencyEdge .}}
+{{end}}{{end}}{{end}}`
+
+// ShowCreateData is the data structure passed to the ShowCreate template
+type ShowCreateData struct {
+	DBName   string            `json:"database"`
+	Objects  []CreateStatement `json:"objects"`
+	NotFound []string          `json:"notFound"` // Requested objects that do not exist
+}
+
+// showCreateTemplate is the output format for show_create. Routines and triggers are wrapped in
+// DELIMITER so that the output can be run as is by the mysql client
+const showCreateTemplate = `{{range $i, $o := .Objects}}{{if $i}}
+{{end}}-- {{$o.Type}} {{$o.Name}}
+{{if or (eq $o.Type "TABLE") (eq $o.Type "VIEW")}}{{$o.Statement}};
+{{else}}DELIMITER ;;
+{{$o.Statement}} ;;
+DELIMITER ;
+{{end}}{{end}}{{if .NotFound}}{{if .Objects}}
+{{end}}-- Not found: {{join .NotFound ", "}}
+{{end}}`
+
+// columnImpactTemplate is the output format for analyze_column_impact
+const columnImpactTemplate = `# Column Impact: {{.Table}}.{{.Column}}
+* Views, triggers and routines are matched by their definition text; review them before changing the column
+
+## Indexes and Keys{{range .Indexes}}
+- {{formatIndexKind .}} {{.Name}}: {{formatIndex (list .)}}{{else}}
+- None{{end}}
+
+## Outgoing Foreign Keys{{range .OutgoingFKs}}
+- {{formatDependencyEdge .}}{{else}}
+- None{{end}}
+
+## Incoming Foreign Keys{{range .IncomingFKs}}
+- {{formatDependencyEdge .}}{{else}}
+- None{{end}}
+
+## Generated Columns{{range .GeneratedColumns}}
+- {{.Name}}: {{.Expression}}{{else}}
+- None{{end}}
+
+## Views{{range .Views}}
+- {{.Name}}{{else}}
+- None{{end}}
+
+## Triggers{{range .Triggers}}
+- {{.Name}} ({{.Timing}} {{.Event}} ON {{.Table}}){{else}}
+- None{{end}}
+
+## Routines{{range .Routines}}
+- {{.Type}} {{.Name}}{{else}}
+- None{{end}}
+`
+
+// schemaDiffTemplate is the output format for diff_schemas
+const schemaDiffTemplate = `Schema diff of database "{{.Target}}"{{with .TargetFile}} in {{.}}{{end}} (target) against "{{.Source}}" (source)
+* + exists only in the source, - exists only in the target, ~ differs (target -> source)
+* A key or index whose definition changed is shown as removed and added
+{{if .IsEmpty}}
+No differences.
+{{end}}{{if .AddedTables}}
+## Added Tables
+{{range .AddedTables -}}
++ {{.Name}}{{if .Comment}} - {{.Comment}}{{end}}
+{{end}}{{end}}{{if .RemovedTables}}
+## Removed Tables
+{{range .RemovedTables -}}
+- {{.Name}}{{if .Comment}} - {{.Comment}}{{end}}
+{{end}}{{end}}{{range .ChangedTables}}
+## Changed Table: {{.Name}}
+{{with .Comment}}~ comment: "{{.Target}}" -> "{{.Source}}"
+{{end}}{{range .AddedColumns}}+ column {{.Name}}: {{formatColumnDefinition .}}
+{{end}}{{range .RemovedColumns}}- column {{.Name}}: {{formatColumnDefinition .}}
+{{end}}{{range .ChangedColumns}}~ column {{.Name}}: {{formatColumnDefinition .Target}} -> {{formatColumnDefinition .Source}}
+{{end}}{{with .PrimaryKey}}~ primary key: {{or (formatPK .Target) "none"}} -> {{or (formatPK .Source) "none"}}
+{{end}}{{range .AddedUniqueKeys}}+ unique key {{.Name}}: {{formatUniqueKey .}}
+{{end}}{{range .RemovedUniqueKeys}}- unique key {{.Name}}: {{formatUniqueKey .}}
+{{end}}{{range .AddedIndexes}}+ index {{.Name}}: {{formatIndex (list .)}}
+{{end}}{{range .RemovedIndexes}}- index {{.Name}}: {{formatIndex (list .)}}
+{{end}}{{range .AddedForeignKeys}}+ foreign key {{.Name}}: {{formatForeignKey .}}
+{{end}}{{range .RemovedForeignKeys}}- foreign key {{.Name}}: {{formatForeignKey .}}
+{{end}}{{end}}`
+
+// ListConnectionsData is the data structure passed to the ListConnections template
+type ListConnectionsData struct {
+	Connections []ConnectionInfo `json:"connections"`
+}
+
+// listConnectionsTemplate is the output format for list_connections
+const listConnectionsTemplate = `Connections (Total: {{len .Connections}})
+{{range .Connections -}}
+- {{.Name}}{{if .Default}} (default){{end}}{{if .Description}} - {{.Description}}{{end}}{{with .Database}} [database: {{.}}]{{end}}
+{{end}}`
+
 var funcMap = template.FuncMap{
-	"formatPK":     formatPK,
-	"formatUK":     formatUK,
-	"formatFK":     formatFK,
-	"formatColumn": formatColumn,
-	"formatIndex":  formatIndex,
+	"formatPK":               formatPK,
+	"formatUK":               formatUK,
+	"formatFK":               formatFK,
+	"formatColumn":           formatColumn,
+	"formatColumnDefinition": formatColumnDefinition,
+	"formatUniqueKey":        func(uk UniqueKey) string { return formatUK([]UniqueKey{uk}) },
+	"formatForeignKey":       func(fk ForeignKey) string { return formatFK([]ForeignKey{fk}) },
+	"formatIndex":            formatIndex,
+	"formatDependencyEdge":   formatDependencyEdge,
+	"formatIndexKind":        formatIndexKind,
+	"list":                   func(idx IndexInfo) []IndexInfo { return []IndexInfo{idx} },
+	"inc":                    func(i int) int { return i + 1 },
+	"join":                   strings.Join,
+	"lower":                  strings.ToLower,
+	"upper":                  strings.ToUpper,
+	"truncate":               truncate,
+	"formatNullable":         formatNullable,
+	"formatDefault":          formatDefault,
 }
 
 // formatPK formats primary key information
@@ -101,24 +266,36 @@ func formatFK(fk []ForeignKey) string {
 			refColStr = fmt.Sprintf("(%s)", refColStr)
 		}
 
-		fkInfo = append(fkInfo, fmt.Sprintf("%s -> %s.%s",
+		info := fmt.Sprintf("%s -> %s.%s",
 			colStr,
 			k.RefTable,
-			refColStr))
+			refColStr)
+		if k.Redacted {
+			info = colStr + " -> (redacted)"
+		}
+		switch k.Origin {
+		case FKOriginInferred:
+			info += fmt.Sprintf(" (inferred %.2f)", k.Confidence)
+		case FKOriginVirtual:
+			info += " (virtual)"
+		}
+		fkInfo = append(fkInfo, info)
 	}
 	return strings.Join(fkInfo, "; ")
 }
 
 // formatColumn formats column information
 func formatColumn(col ColumnInfo) string {
-	nullable := "NOT NULL"
-	if col.IsNullable == "YES" {
-		nullable = "NULL"
-	}
+	return fmt.Sprintf("- %s: %s", col.Name, formatColumnDefinition(col))
+}
+
+// formatColumnDefinition formats the type, nullability, default value and comment of a column
+func formatColumnDefinition(col ColumnInfo) string {
+	nullable := formatNullable(col)
 
 	defaultValue := ""
 	if col.Default.Valid {
-		defaultValue = fmt.Sprintf(" DEFAULT %s", col.Default.String)
+		defaultValue = fmt.Sprintf(" DEFAULT %s", formatDefault(col))
 	}
 
 	comment := ""
@@ -126,8 +303,35 @@ func formatColumn(col ColumnInfo) string {
 		comment = fmt.Sprintf(" [%s]", col.Comment)
 	}
 
-	return fmt.Sprintf("- %s: %s %s%s%s",
-		col.Name, col.Type, nullable, defaultValue, comment)
+	return fmt.Sprintf("%s %s%s%s", col.Type, nullable, defaultValue, comment)
+}
+
+// formatNullable returns NULL or NOT NULL
+func formatNullable(col ColumnInfo) string {
+	if col.IsNullable == "YES" {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+// formatDefault returns the default value of a column, or an empty string when it has none
+func formatDefault(col ColumnInfo) string {
+	if !col.Default.Valid {
+		return ""
+	}
+	return col.Default.String
+}
+
+// truncate shortens s to at most n characters, adding "..." when it is cut
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
 }
 
 func formatIndex(idx []IndexInfo) string {
@@ -144,3 +348,131 @@ func formatIndex(idx []IndexInfo) string {
 	}
 	return strings.Join(idxInfo, "; ")
 }
+
+// formatDependencyEdge formats a foreign key that causes a dependency, with its constraint name
+func formatDependencyEdge(e DependencyEdge) string {
+	return fmt.Sprintf("%s: %s [%s]", e.Table, formatFK([]ForeignKey{e.FK}), e.FK.Name)
+}
+
+// formatIndexKind returns the kind of an index: PRIMARY KEY, UNIQUE or INDEX
+func formatIndexKind(idx IndexInfo) string {
+	switch {
+	case idx.Name == "PRIMARY":
+		return "PRIMARY KEY"
+	case idx.Unique:
+		return "UNIQUE"
+	default:
+		return "INDEX"
+	}
+}
+
+// tableNameJSON is the JSON representation of a table in list_tables with detail "names"
+type tableNameJSON struct {
+	Name string `json:"name"`
+}
+
+// tableCommentJSON is the JSON representation of a table in list_tables with detail "names+comments"
+type tableCommentJSON struct {
+	Name    string `json:"name"`
+	Comment string `json:"comment"`
+}
+
+// MarshalJSON outputs only the table fields of the detail level, so that keys that were not fetched are not shown as empty
+func (d ListTablesData) MarshalJSON() ([]byte, error) {
+	type alias ListTablesData
+	var tables any = emptyIfNil(d.Tables)
+	switch d.Detail {
+	case detailNames:
+		names := make([]tableNameJSON, 0, len(d.Tables))
+		for _, t := range d.Tables {
+			names = append(names, tableNameJSON{Name: t.Name})
+		}
+		tables = names
+	case detailNamesComments:
+		comments := make([]tableCommentJSON, 0, len(d.Tables))
+		for _, t := range d.Tables {
+			comments = append(comments, tableCommentJSON{Name: t.Name, Comment: t.Comment})
+		}
+		tables = comments
+	}
+	return json.Marshal(struct {
+		alias
+		Tables any `json:"tables"`
+	}{alias(d), tables})
+}
+
+// MarshalJSON outputs list fields as empty arrays instead of null so that the JSON schema is stable
+func (t TableSummary) MarshalJSON() ([]byte, error) {
+	type alias TableSummary
+	a := alias(t)
+	a.PK, a.UK, a.FK = emptyIfNil(a.PK), emptyIfNil(a.UK), emptyIfNil(a.FK)
+	return json.Marshal(a)
+}
+
+// MarshalJSON outputs list fields as empty arrays instead of null so that the JSON schema is stable
+func (t TableDetail) MarshalJSON() ([]byte, error) {
+	type alias TableDetail
+	a := alias(t)
+	a.Columns, a.PrimaryKeys, a.UniqueKeys = emptyIfNil(a.Columns), emptyIfNil(a.PrimaryKeys), emptyIfNil(a.UniqueKeys)
+	a.ForeignKeys, a.Indexes = emptyIfNil(a.ForeignKeys), emptyIfNil(a.Indexes)
+	return json.Marshal(a)
+}
+
+// MarshalJSON outputs list fields as empty arrays instead of null so that the JSON schema is stable
+func (c ColumnImpact) MarshalJSON() ([]byte, error) {
+	type alias ColumnImpact
+	a := alias(c)
+	a.Indexes, a.OutgoingFKs, a.IncomingFKs = emptyIfNil(a.Indexes), emptyIfNil(a.OutgoingFKs), emptyIfNil(a.IncomingFKs)
+	a.GeneratedColumns = emptyIfNil(a.GeneratedColumns)
+	a.Views, a.Triggers, a.Routines = emptyIfNil(a.Views), emptyIfNil(a.Triggers), emptyIfNil(a.Routines)
+	return json.Marshal(a)
+}
+
+// columnInfoJSON is the JSON representation of ColumnInfo
+type columnInfoJSON struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default"` // null when the column has no default
+	Comment  string  `json:"comment"`
+}
+
+// MarshalJSON outputs nullability as a boolean and the default value as a string or null
+func (c ColumnInfo) MarshalJSON() ([]byte, error) {
+	var defaultValue *string
+	if c.Default.Valid {
+		defaultValue = &c.Default.String
+	}
+	return json.Marshal(columnInfoJSON{
+		Name:     c.Name,
+		Type:     c.Type,
+		Nullable: c.IsNullable == "YES",
+		Default:  defaultValue,
+		Comment:  c.Comment,
+	})
+}
+
+// UnmarshalJSON reads the representation written by MarshalJSON, e.g. from a schema snapshot
+func (c *ColumnInfo) UnmarshalJSON(data []byte) error {
+	var v columnInfoJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*c = ColumnInfo{Name: v.Name, Type: v.Type, IsNullable: "NO", Comment: v.Comment}
+	if v.Nullable {
+		c.IsNullable = "YES"
+	}
+	if v.Default != nil {
+		c.Default = sql.NullString{String: *v.Default, Valid: true}
+	}
+	return nil
+}
+
+// emptyIfNil returns an empty slice instead of nil
+func emptyIfNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableSummaryJSON(t *testing.T) {
+	data, err := json.Marshal(ListTablesData{
+		DBName:   "ecshop",
+		Detail:   detailKeys,
+		Total:    2,
+		Returned: 2,
+		Tables: []TableSummary{
+			{Name: "users", Comment: "User information", PK: []string{"id"}},
+			{Name: "orders", PK: []string{"id"}, FK: []ForeignKey{
+				{Name: "inferred_orders_user_id", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, Origin: FKOriginInferred, Confidence: 0.9},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"database": "ecshop",
+		"detail": "keys",
+		"total": 2,
+		"returned": 2,
+		"tables": [
+			{"name": "users", "comment": "User information", "primaryKey": ["id"], "uniqueKeys": [], "foreignKeys": []},
+			{"name": "orders", "comment": "", "primaryKey": ["id"], "uniqueKeys": [], "foreignKeys": [
+				{"name": "inferred_orders_user_id", "columns": ["user_id"], "refTable": "users", "refColumns": ["id"], "origin": "inferred", "confidence": 0.9}
+			]}
+		]
+	}`, string(data))
+}
+
+func TestTableDetailJSON(t *testing.T) {
+	data, err := json.Marshal(TableDetail{
+		Name: "users",
+		Columns: []ColumnInfo{
+			{Name: "id", Type: "int", IsNullable: "NO", Comment: "User ID"},
+			{Name: "status", Type: "varchar(10)", IsNullable: "YES", Default: sql.NullString{String: "active", Valid: true}},
+		},
+		PrimaryKeys: []string{"id"},
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"name": "users",
+		"comment": "",
+		"columns": [
+			{"name": "id", "type": "int", "nullable": false, "default": null, "comment": "User ID"},
+			{"name": "status", "type": "varchar(10)", "nullable": true, "default": "active", "comment": ""}
+		],
+		"primaryKey": ["id"],
+		"uniqueKeys": [],
+		"foreignKeys": [],
+		"indexes": []
+	}`, string(data))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VirtualForeignKey is a relationship declared in a config file that the database doesn't enforce
+type VirtualForeignKey struct {
+	Name        string
+	Database    string // Empty means the relationship applies to any database
+	Table       string
+	Columns     []string
+	RefDatabase string // Empty means the same database as the source table
+	RefTable    string
+	RefColumns  []string
+}
+
+// virtualForeignKeyFile is the structure of the virtual foreign key config file (YAML or JSON)
+type virtualForeignKeyFile struct {
+	ForeignKeys []struct {
+		Name string `yaml:"name"`
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
+	} `yaml:"foreignKeys"`
+}
+
+// LoadVirtualForeignKeys loads virtual foreign keys from a YAML or JSON file such as:
+//
+//	foreignKeys:
+//	  - from: orders.customer_ref
+//	    to: crm.customers.id
+//	  - from: ecshop.order_items.(maker, code)
+//	    to: products.(maker_code, internal_code)
+func LoadVirtualForeignKeys(path string) ([]VirtualForeignKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file virtualForeignKeyFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var vfks []VirtualForeignKey
+	for i, def := range file.ForeignKeys {
+		db, table, columns, err := parseColumnReference(def.From)
+		if err != nil {
+			return nil, fmt.Errorf("%s: foreignKeys[%d].from: %w", path, i, err)
+		}
+		refDB, refTable, refColumns, err := parseColumnReference(def.To)
+		if err != nil {
+			return nil, fmt.Errorf("%s: foreignKeys[%d].to: %w", path, i, err)
+		}
+		if len(columns) != len(refColumns) {
+			return nil, fmt.Errorf("%s: foreignKeys[%d]: %d columns reference %d columns", path, i, len(columns), len(refColumns))
+		}
+
+		name := def.Name
+		if name == "" {
+			name = "virtual_" + table + "_" + strings.Join(columns, "_")
+		}
+
+		vfks = append(vfks, VirtualForeignKey{
+			Name:        name,
+			Database:    db,
+			Table:       table,
+			Columns:     columns,
+			RefDatabase: refDB,
+			RefTable:    refTable,
+			RefColumns:  refColumns,
+		})
+	}
+
+	return vfks, nil
+}
+
+// parseColumnReference parses "[db.]table.column" or "[db.]table.(col1, col2)"
+func parseColumnReference(ref string) (db string, table string, columns []string, err error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", "", nil, fmt.Errorf("reference is empty")
+	}
+
+	var path string
+	if strings.HasSuffix(ref, ")") {
+		open := strings.LastIndex(ref, ".(")
+		if open < 0 {
+			return "", "", nil, fmt.Errorf("invalid reference %q", ref)
+		}
+		path = ref[:open]
+		for _, c := range strings.Split(ref[open+2:len(ref)-1], ",") {
+			columns = append(columns, strings.TrimSpace(c))
+		}
+	} else {
+		dot := strings.LastIndex(ref, ".")
+		if dot < 0 {
+			return "", "", nil, fmt.Errorf("invalid reference %q: expected table.column", ref)
+		}
+		path = ref[:dot]
+		columns = []string{ref[dot+1:]}
+	}
+
+	parts := strings.Split(path, ".")
+	switch len(parts) {
+	case 1:
+		table = parts[0]
+	case 2:
+		db, table = parts[0], parts[1]
+	default:
+		return "", "", nil, fmt.Errorf("invalid reference %q: expected [db.]table.column", ref)
+	}
+
+	if table == "" {
+		return "", "", nil, fmt.Errorf("invalid reference %q: table is empty", ref)
+	}
+	for _, c := range columns {
+		if c == "" {
+			return "", "", nil, fmt.Errorf("invalid reference %q: column is empty", ref)
+		}
+	}
+
+	return db, table, columns, nil
+}
+
+// ApplyVirtualForeignKeys appends the virtual foreign keys that apply to dbName to the FK list of each table.
+// References to another database are shown with the database name, e.g. crm.customers.
+func ApplyVirtualForeignKeys(tables []TableSummary, dbName string, vfks []VirtualForeignKey) {
+	for i := range tables {
+		for _, vfk := range vfks {
+			if vfk.Database != "" && vfk.Database != dbName {
+				continue
+			}
+			if vfk.Table != tables[i].Name {
+				continue
+			}
+
+			refTable := vfk.RefTable
+			if vfk.RefDatabase != "" && vfk.RefDatabase != dbName {
+				refTable = vfk.RefDatabase + "." + vfk.RefTable
+			}
+
+			tables[i].FK = append(tables[i].FK, ForeignKey{
+				Name:       vfk.Name,
+				Columns:    vfk.Columns,
+				RefTable:   refTable,
+				RefColumns: vfk.RefColumns,
+				Origin:     FKOriginVirtual,
+			})
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadVirtualForeignKeys(t *testing.T) {
+	vfks, err := LoadVirtualForeignKeys("testdata/virtual_foreign_keys.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []VirtualForeignKey{
+		{Name: "virtual_orders_user_id", Table: "orders", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+		{Name: "fk_order_customer", Database: testDBName, Table: "orders", Columns: []string{"shop_code", "category_code"}, RefDatabase: "crm", RefTable: "shop_categories", RefColumns: []string{"shop_code", "category_code"}},
+		{Name: "virtual_orders_note_id", Database: "other_db", Table: "orders", Columns: []string{"note_id"}, RefTable: "notes", RefColumns: []string{"id"}},
+	}, vfks)
+}
+
+func TestParseColumnReference_Invalid(t *testing.T) {
+	for _, ref := range []string{"", "orders", "a.b.c.d", "orders.(a, )", ".id"} {
+		_, _, _, err := parseColumnReference(ref)
+		assert.Error(t, err, "reference %q should be invalid", ref)
+	}
+}
+
+func TestApplyVirtualForeignKeys(t *testing.T) {
+	vfks, err := LoadVirtualForeignKeys("testdata/virtual_foreign_keys.yaml")
+	require.NoError(t, err)
+
+	tables := []TableSummary{{Name: "orders"}, {Name: "users"}}
+	ApplyVirtualForeignKeys(tables, testDBName, vfks)
+
+	assert.Equal(t, []ForeignKey{
+		{Name: "virtual_orders_user_id", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, Origin: FKOriginVirtual},
+		{Name: "fk_order_customer", Columns: []string{"shop_code", "category_code"}, RefTable: "crm.shop_categories", RefColumns: []string{"shop_code", "category_code"}, Origin: FKOriginVirtual},
+	}, tables[0].FK)
+	assert.Empty(t, tables[1].FK)
+}